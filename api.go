@@ -6,8 +6,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -50,6 +52,20 @@ type Client struct {
 	requestType     ContentType
 	responseType    ContentType
 	rateLimitConfig *RateLimitConfig
+	authenticator   Authenticator
+	logger          *slog.Logger
+	logBodyLimit    int
+	tracer          Tracer
+	middlewares     []Middleware
+	cache           *Cache
+	flights         *flightGroup
+	hooks           hooks
+	compression     *CompressionConfig
+	basePool        *baseURLPool
+	idempotency     *IdempotencyConfig
+	idempotencyKeys *idempotencyKeys
+	dryRun          *DryRun
+	connStats       bool
 }
 
 // NewClient creates a new API client with default configuration
@@ -63,6 +79,8 @@ func NewClient(baseURL string) *Client {
 		},
 		headers:         make(map[string]string),
 		rateLimitConfig: DefaultRateLimitConfig(),
+		logBodyLimit:    defaultLogBodyLimit,
+		idempotencyKeys: newIdempotencyKeys(),
 	}
 }
 
@@ -98,6 +116,12 @@ func (c *Client) SetBasicAuth(username, password string) {
 	c.headers["Authorization"] = fmt.Sprintf("Basic %s", encodedAuth)
 }
 
+// SetAuthenticator sets an authenticator that is applied to every request
+// attempt after all headers have been set
+func (c *Client) SetAuthenticator(auth Authenticator) {
+	c.authenticator = auth
+}
+
 // SetHeader sets a custom header
 func (c *Client) SetHeader(key, value string) {
 	c.headers[key] = value
@@ -129,89 +153,354 @@ func (c *Client) Post(ctx context.Context, path string, body, result interface{}
 	return c.doRequest(ctx, http.MethodPost, path, reqBody, result)
 }
 
-// doRequest performs the actual HTTP request with rate limiting support
-func (c *Client) doRequest(ctx context.Context, method, path string, body []byte, result interface{}) error {
+// Do performs a request with the given method
+func (c *Client) Do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		if b, ok := body.([]byte); ok {
+			reqBody = b
+		} else {
+			return fmt.Errorf("body must be []byte")
+		}
+	}
+	return c.doRequest(ctx, method, path, reqBody, result)
+}
+
+// newRequest builds a single request attempt. A fresh request is created for
+// every attempt so that the body can be re-sent and re-signed on retries.
+// base is the base URL to send it to and encoding is the content encoding
+// body is already compressed with, if any.
+func (c *Client) newRequest(ctx context.Context, base, method, path string, body []byte, encoding string) (*http.Request, error) {
 	var reqBody io.Reader
 	if body != nil {
-		reqBody = bytes.NewBuffer(body)
+		reqBody = bytes.NewReader(body)
 	}
-	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, base+path, reqBody)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	// Set content type headers
 	req.Header.Set("Content-Type", string(c.requestType))
-	req.Header.Set("Accept", string(c.responseType))
+	req.Header.Set("Accept", string(c.responseTypeFor(ctx)))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
 
 	// Set custom headers
 	for key, value := range c.headers {
 		req.Header.Set(key, value)
 	}
+	for key, values := range headersFromContext(ctx) {
+		req.Header[key] = values
+	}
 
-	// Retry loop for rate limiting
-	var resp *http.Response
-	for retry := 0; retry <= c.rateLimitConfig.MaxRetries; retry++ {
-		resp, err = c.httpClient.Do(req)
+	// Propagate trace context
+	if span := spanFromContext(ctx); span != nil {
+		if traceParent := span.TraceParent(); traceParent != "" {
+			req.Header.Set("Traceparent", traceParent)
+		}
+	}
+
+	if err := c.hooks.runBeforeRequest(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequestAborted, err)
+	}
+
+	if c.authenticator != nil {
+		if err := c.authenticator.Authenticate(req, body); err != nil {
+			return nil, fmt.Errorf("error authenticating request: %w", err)
+		}
+	}
+
+	return req, nil
+}
+
+// attempt performs a single request attempt within its own span. wait is the
+// rate limit wait that preceded this attempt.
+func (c *Client) attempt(ctx context.Context, base, method, path, route string, body []byte, encoding string, n int, wait time.Duration) (*http.Request, *http.Response, error) {
+	ctx = context.WithValue(ctx, requestInfoKey{}, RequestInfo{
+		Method:        method,
+		Route:         route,
+		Attempt:       n,
+		RateLimitWait: wait,
+	})
+	ctx, span := c.startSpan(ctx, method+" "+route+" attempt")
+	defer span.End()
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.route", route)
+	span.SetAttribute("attempt", n)
+	if wait > 0 {
+		span.SetAttribute("rate_limit.wait_ms", wait.Milliseconds())
+	}
+
+	if c.connStats {
+		ctx = withConnTrace(ctx)
+	}
+	req, err := c.newRequest(ctx, base, method, path, body, encoding)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		err = &sendError{fmt.Errorf("error performing request: %w", err)}
+		span.RecordError(err)
+		return req, nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if trace, ok := ctx.Value(connStatsKey{}).(*connTrace); ok {
+		trace.setProtocol(resp.Proto)
+		if stats, ok := connStatsFromContext(ctx); ok {
+			span.SetAttribute("http.protocol", stats.Protocol)
+			span.SetAttribute("conn.reused", stats.Reused)
+			span.SetAttribute("conn.dns_ms", stats.DNS.Milliseconds())
+			span.SetAttribute("conn.connect_ms", stats.Connect.Milliseconds())
+			span.SetAttribute("conn.tls_ms", stats.TLS.Milliseconds())
+			span.SetAttribute("conn.ttfb_ms", stats.TTFB.Milliseconds())
+		}
+	}
+	c.hooks.runAfterResponse(resp)
+	return req, resp, nil
+}
+
+// doRequest performs the actual HTTP request, coalescing identical
+// concurrent GET requests when single-flight is enabled
+func (c *Client) doRequest(ctx context.Context, method, path string, body []byte, result interface{}) error {
+	ctx, path, err := resolvePath(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if c.flights == nil || method != http.MethodGet {
+		_, err := c.execute(ctx, method, path, body, result)
+		return err
+	}
+
+	var leaderErr error
+	respBody, shared, err := c.flights.do(ctx, c.flightKey(ctx, method, path, body), func() ([]byte, error) {
+		respBody, err := c.execute(ctx, method, path, body, result)
+		var decodeErr *decodeError
+		if errors.As(err, &decodeErr) {
+			// The response was fetched; only decoding into the leader's result failed
+			leaderErr = err
+			return respBody, nil
+		}
+		return respBody, err
+	})
+	if err != nil {
+		return err
+	}
+	if !shared {
+		return leaderErr
+	}
+	if len(respBody) == 0 {
+		// The leader got no content, e.g. a 204, and decoded nothing either
+		return nil
+	}
+	return c.decodeAs(c.responseTypeFor(ctx), respBody, result)
+}
+
+// execute performs the HTTP request with rate limiting support and decodes
+// the response into result
+func (c *Client) execute(ctx context.Context, method, path string, body []byte, result interface{}) ([]byte, error) {
+	return c.call(ctx, method, path, body, func(resp *http.Response) ([]byte, error) {
+		if resp.StatusCode >= 400 {
+			return responseError(resp)
+		}
+		if resp.StatusCode == http.StatusNoContent {
+			return nil, nil
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return fmt.Errorf("error performing request: %w", err)
-		}
-
-		// Check for rate limit header
-		resetTime := resp.Header.Get(c.rateLimitConfig.HeaderName)
-		if resetTime != "" {
-			// Parse the reset time
-			resetUnix, err := strconv.ParseInt(resetTime, 10, 64)
-			if err != nil {
-				// If we can't parse the reset time, use default wait time
-				time.Sleep(c.rateLimitConfig.DefaultWaitTime)
-				continue
+			return nil, fmt.Errorf("error reading response body: %w", err)
+		}
+
+		return respBody, c.decodeAs(c.responseTypeFor(ctx), respBody, result)
+	})
+}
+
+// call performs the HTTP request with rate limiting support and passes the
+// final response to handle. The body handle returns is logged.
+func (c *Client) call(ctx context.Context, method, path string, body []byte, handle func(resp *http.Response) ([]byte, error)) (respBody []byte, err error) {
+	start := time.Now()
+	ctx = c.withCredentialNames(ctx)
+	route := routeFor(ctx, path)
+	ctx, span := c.startSpan(ctx, method+" "+route)
+	var (
+		lastReq       *http.Request
+		status        int
+		attempts      int
+		rateLimitWait time.Duration
+	)
+	defer func() {
+		span.SetAttribute("http.method", method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", status)
+		span.SetAttribute("retry_count", max(attempts-1, 0))
+		span.SetAttribute("rate_limit.wait_ms", rateLimitWait.Milliseconds())
+		if err != nil {
+			span.RecordError(err)
+			c.hooks.runOnError(lastReq, err)
+		}
+		span.End()
+		c.logRequest(ctx, lastReq, method, path, body, status, respBody, attempts, time.Since(start), err)
+	}()
+
+	// Compress once so every attempt sends and signs the same bytes
+	sendBody, encoding, err := c.compressBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send one idempotency key with every attempt, and only repeat attempts
+	// that may have reached the server if the call is safe to re-send
+	key, provided := c.idempotencyKey(ctx, method)
+	retrySafe := c.retrySafe(ctx, method, key)
+	unresolved := false
+	if key != "" {
+		ctx = withHeaders(ctx, http.Header{c.idempotencyHeader(): {key}})
+	}
+	if provided {
+		if err := c.idempotencyKeys.begin(key, retrySafe); err != nil {
+			return nil, err
+		}
+		defer func() { c.idempotencyKeys.end(key, unresolved, c.rememberUnresolved()) }()
+	}
+
+	// Retry loop for rate limiting and base URL failover
+	config := c.rateLimit()
+	maxRetries := max(config.MaxRetries, 0)
+	var resp *http.Response
+	var wait time.Duration
+	renewed := false
+	for retries, failovers := 0, 0; ; {
+		attempts++
+		base := c.pickBaseURL()
+		req, r, err := c.attempt(ctx, base, method, path, route, sendBody, encoding, attempts, wait)
+		if req != nil {
+			lastReq = req
+		}
+		wait = 0
+		unresolved = mayHaveBeenProcessed(r, err)
+		if c.failover(ctx, base, r, err, failovers) && (retrySafe || !unresolved) {
+			failovers++
+			c.logFailover(ctx, method, base, path, attempts, err, r)
+			if r != nil {
+				drainBody(r.Body)
 			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp = r
+		status = resp.StatusCode
 
-			// Calculate wait time
-			waitTime := time.Until(time.Unix(resetUnix, 0))
-			if waitTime > 0 {
-				// Wait for the specified time
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(waitTime):
-					// Continue with retry
-					continue
-				}
+		// Renew an expired session once and send the rejected call again
+		if renewer, ok := c.authenticator.(sessionRenewer); ok && status == http.StatusUnauthorized && !renewed && ctx.Value(sessionRequestKey{}) == nil {
+			drainBody(resp.Body)
+			if err := renewer.renew(ctx, lastReq); err != nil {
+				return nil, fmt.Errorf("error authenticating request: %w", err)
 			}
+			renewed = true
+			continue
+		}
+
+		// Stop unless rate limited with retries left. Calls that are not safe
+		// to re-send are only retried if the server rejected them outright.
+		wait = rateLimitWaitTime(resp, config)
+		if !retrySafe && status != http.StatusTooManyRequests && status != http.StatusServiceUnavailable {
+			wait = 0
+		}
+		if wait <= 0 || retries >= maxRetries {
+			break
+		}
+
+		retries++
+		rateLimitWait += wait
+		c.logRetry(ctx, lastReq, attempts, wait)
+		c.hooks.runOnRetry(resp, attempts, wait)
+
+		// Drain the discarded response so the connection can be reused
+		drainBody(resp.Body)
+
+		// Wait for the specified time
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
 		}
+	}
+
+	// Drain whatever handle leaves unread so the connection can be reused
+	defer drainBody(resp.Body)
+	return handle(resp)
+}
+
+// responseError reads a failed response and returns its body and an error
+// describing it
+func responseError(resp *http.Response) ([]byte, error) {
+	respBody, _ := io.ReadAll(resp.Body)
+	return respBody, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
+}
 
-		// If we get here, either there was no rate limit or we've waited
-		break
+// rateLimit returns the rate limit configuration, falling back to the
+// defaults if none is set
+func (c *Client) rateLimit() *RateLimitConfig {
+	if c.rateLimitConfig == nil {
+		return DefaultRateLimitConfig()
 	}
+	return c.rateLimitConfig
+}
 
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+// rateLimitWaitTime returns how long to wait before retrying a rate limited
+// response, or zero if the response is not rate limited
+func rateLimitWaitTime(resp *http.Response, config *RateLimitConfig) time.Duration {
+	resetTime := resp.Header.Get(config.HeaderName)
+	if resetTime == "" {
+		return 0
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
+	// If we can't parse the reset time, use default wait time
+	resetUnix, err := strconv.ParseInt(resetTime, 10, 64)
 	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+		return config.DefaultWaitTime
 	}
+	return time.Until(time.Unix(resetUnix, 0))
+}
 
-	//fmt.Println("Response body: ", string(respBody))
+// decodeError reports a response that was received but could not be decoded
+type decodeError struct {
+	err error
+}
 
-	if result != nil {
-		switch c.responseType {
-		case ContentTypeJSON:
-			if err := json.Unmarshal(respBody, result); err != nil {
-				return fmt.Errorf("error decoding JSON response: %w", err)
-			}
-		case ContentTypeXML:
-			if err := xml.Unmarshal(respBody, result); err != nil {
-				return fmt.Errorf("error decoding XML response: %w", err)
-			}
-		default:
-			return fmt.Errorf("unsupported response content type: %s", c.responseType)
+func (e *decodeError) Error() string { return e.err.Error() }
+func (e *decodeError) Unwrap() error { return e.err }
+
+// decode decodes a response body into result according to the response type
+func (c *Client) decode(respBody []byte, result interface{}) error {
+	return c.decodeAs(c.responseType, respBody, result)
+}
+
+// decodeAs decodes a response body into result according to contentType
+func (c *Client) decodeAs(contentType ContentType, respBody []byte, result interface{}) error {
+	if result == nil {
+		return nil
+	}
+
+	switch contentType {
+	case ContentTypeJSON:
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return &decodeError{fmt.Errorf("error decoding JSON response: %w", err)}
+		}
+	case ContentTypeXML:
+		if err := xml.Unmarshal(respBody, result); err != nil {
+			return &decodeError{fmt.Errorf("error decoding XML response: %w", err)}
 		}
+	default:
+		return &decodeError{fmt.Errorf("unsupported response content type: %s", contentType)}
 	}
 
 	return nil
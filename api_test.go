@@ -0,0 +1,529 @@
+package godefaultapi
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	ID      int      `json:"id" xml:"id"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+// testRateLimitConfig retries quickly so tests stay fast
+func testRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		HeaderName:      "X-RateLimit-Reset",
+		MaxRetries:      3,
+		DefaultWaitTime: time.Millisecond,
+	}
+}
+
+func TestClientMethods(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		call     func(c *Client, result interface{}) error
+		wantBody string
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			call: func(c *Client, result interface{}) error {
+				return c.Get(context.Background(), "/users/1", nil, result)
+			},
+		},
+		{
+			name:   "get with body",
+			method: http.MethodGet,
+			call: func(c *Client, result interface{}) error {
+				return c.Get(context.Background(), "/users/1", []byte(`{"q":1}`), result)
+			},
+			wantBody: `{"q":1}`,
+		},
+		{
+			name:   "post",
+			method: http.MethodPost,
+			call: func(c *Client, result interface{}) error {
+				return c.Post(context.Background(), "/users/1", []byte(`{"name":"John"}`), result)
+			},
+			wantBody: `{"name":"John"}`,
+		},
+		{
+			name:   "do put",
+			method: http.MethodPut,
+			call: func(c *Client, result interface{}) error {
+				return c.Do(context.Background(), http.MethodPut, "/users/1", []byte(`{"name":"Jane"}`), result)
+			},
+			wantBody: `{"name":"Jane"}`,
+		},
+		{
+			name:   "do delete",
+			method: http.MethodDelete,
+			call: func(c *Client, result interface{}) error {
+				return c.Do(context.Background(), http.MethodDelete, "/users/1", nil, result)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tt.method {
+					t.Errorf("method = %s, want %s", r.Method, tt.method)
+				}
+				if r.URL.Path != "/users/1" {
+					t.Errorf("path = %s, want /users/1", r.URL.Path)
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.wantBody {
+					t.Errorf("body = %q, want %q", body, tt.wantBody)
+				}
+				w.Write([]byte(`{"id":1,"name":"John"}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.SetResponseType(ContentTypeJSON)
+
+			var user testUser
+			if err := tt.call(client, &user); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if user.ID != 1 || user.Name != "John" {
+				t.Errorf("user = %+v", user)
+			}
+		})
+	}
+}
+
+func TestClientRejectsNonByteBody(t *testing.T) {
+	client := NewClient("http://127.0.0.1:0")
+	ctx := context.Background()
+
+	for name, err := range map[string]error{
+		"get":  client.Get(ctx, "/", "body", nil),
+		"post": client.Post(ctx, "/", map[string]string{}, nil),
+		"do":   client.Do(ctx, http.MethodPut, "/", 42, nil),
+	} {
+		if err == nil || err.Error() != "body must be []byte" {
+			t.Errorf("%s: error = %v, want body must be []byte", name, err)
+		}
+	}
+}
+
+func TestClientAuthAndHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(c *Client)
+		header    string
+		want      string
+	}{
+		{
+			name:      "bearer token",
+			configure: func(c *Client) { c.SetBearerToken("abc123") },
+			header:    "Authorization",
+			want:      "Bearer abc123",
+		},
+		{
+			name:      "basic auth",
+			configure: func(c *Client) { c.SetBasicAuth("user", "pass") },
+			header:    "Authorization",
+			want:      "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:      "custom header",
+			configure: func(c *Client) { c.SetHeader("X-Requested-With", "GOQualysAPI") },
+			header:    "X-Requested-With",
+			want:      "GOQualysAPI",
+		},
+		{
+			name: "authenticator overrides headers",
+			configure: func(c *Client) {
+				c.SetBearerToken("abc123")
+				c.SetAuthenticator(AuthenticatorFunc(func(req *http.Request, body []byte) error {
+					req.Header.Set("Authorization", "Custom xyz")
+					return nil
+				}))
+			},
+			header: "Authorization",
+			want:   "Custom xyz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get(tt.header); got != tt.want {
+					t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			tt.configure(client)
+			if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestClientAuthenticatorError(t *testing.T) {
+	client := NewClient("http://127.0.0.1:0")
+	client.SetAuthenticator(AuthenticatorFunc(func(req *http.Request, body []byte) error {
+		return errors.New("no credentials")
+	}))
+
+	err := client.Get(context.Background(), "/", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "error authenticating request: no credentials") {
+		t.Errorf("Get() error = %v", err)
+	}
+}
+
+func TestClientContentTypes(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestType  ContentType
+		responseType ContentType
+		setContent   bool
+		response     string
+		wantErr      string
+	}{
+		{
+			name:         "json request, xml response (defaults)",
+			requestType:  ContentTypeJSON,
+			responseType: ContentTypeXML,
+			response:     `<user><id>1</id><name>John</name></user>`,
+		},
+		{
+			name:         "json response",
+			requestType:  ContentTypeJSON,
+			responseType: ContentTypeJSON,
+			response:     `{"id":1,"name":"John"}`,
+		},
+		{
+			name:         "set content type",
+			requestType:  ContentTypeXML,
+			responseType: ContentTypeXML,
+			setContent:   true,
+			response:     `<user><id>1</id><name>John</name></user>`,
+		},
+		{
+			name:         "invalid json",
+			requestType:  ContentTypeJSON,
+			responseType: ContentTypeJSON,
+			response:     `{"id":`,
+			wantErr:      "error decoding JSON response",
+		},
+		{
+			name:         "invalid xml",
+			requestType:  ContentTypeJSON,
+			responseType: ContentTypeXML,
+			response:     `<user><id>1</id>`,
+			wantErr:      "error decoding XML response",
+		},
+		{
+			name:         "unsupported response type",
+			requestType:  ContentTypeJSON,
+			responseType: ContentType("text/csv"),
+			response:     `id,name`,
+			wantErr:      "unsupported response content type: text/csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Content-Type"); got != string(tt.requestType) {
+					t.Errorf("Content-Type = %q, want %q", got, tt.requestType)
+				}
+				if got := r.Header.Get("Accept"); got != string(tt.responseType) {
+					t.Errorf("Accept = %q, want %q", got, tt.responseType)
+				}
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			if tt.setContent {
+				client.SetContentType(tt.requestType)
+			} else {
+				client.SetRequestType(tt.requestType)
+			}
+			client.SetResponseType(tt.responseType)
+
+			var user testUser
+			err := client.Get(context.Background(), "/", nil, &user)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Get() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if user.ID != 1 || user.Name != "John" {
+				t.Errorf("user = %+v", user)
+			}
+		})
+	}
+}
+
+func TestClientNilResultSkipsDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`not xml`))
+	}))
+	defer server.Close()
+
+	if err := NewClient(server.URL).Get(context.Background(), "/", nil, nil); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		reset        func(attempt int) string
+		maxRetries   int
+		wantAttempts int32
+		wantErr      string
+	}{
+		{
+			name:         "no rate limit header",
+			reset:        func(int) string { return "" },
+			maxRetries:   3,
+			wantAttempts: 1,
+		},
+		{
+			name: "unparseable reset uses default wait",
+			reset: func(attempt int) string {
+				if attempt == 1 {
+					return "soon"
+				}
+				return ""
+			},
+			maxRetries:   3,
+			wantAttempts: 2,
+		},
+		{
+			name: "future reset waits and retries",
+			reset: func(attempt int) string {
+				if attempt == 1 {
+					return strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10)
+				}
+				return ""
+			},
+			maxRetries:   3,
+			wantAttempts: 2,
+		},
+		{
+			name:         "past reset does not retry",
+			reset:        func(int) string { return strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10) },
+			maxRetries:   3,
+			wantAttempts: 1,
+			wantErr:      "request failed with status 429",
+		},
+		{
+			name:         "retries exhausted returns last response",
+			reset:        func(int) string { return "soon" },
+			maxRetries:   2,
+			wantAttempts: 3,
+			wantErr:      "request failed with status 429: slow down",
+		},
+		{
+			name:         "negative max retries makes one attempt",
+			reset:        func(int) string { return "soon" },
+			maxRetries:   -1,
+			wantAttempts: 1,
+			wantErr:      "request failed with status 429",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+				if reset := tt.reset(int(n)); reset != "" {
+					w.Header().Set("X-RateLimit-Reset", reset)
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write([]byte("slow down"))
+					return
+				}
+				w.Write([]byte(`<user><id>1</id></user>`))
+			}))
+			defer server.Close()
+
+			config := testRateLimitConfig()
+			config.MaxRetries = tt.maxRetries
+			client := NewClient(server.URL)
+			client.SetRateLimitConfig(config)
+
+			var user testUser
+			err := client.Get(context.Background(), "/", nil, &user)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Get() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestClientNilRateLimitConfigUsesDefaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetRateLimitConfig(nil)
+	if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+}
+
+func TestClientContextCancellation(t *testing.T) {
+	t.Run("cancelled during rate limit wait", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := client.Get(ctx, "/", nil, nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Get() error = %v, want context.DeadlineExceeded", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("Get() did not return promptly after cancellation")
+		}
+	})
+
+	t.Run("cancelled during unparseable reset wait", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Reset", "soon")
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+		config := testRateLimitConfig()
+		config.DefaultWaitTime = time.Hour
+		client.SetRateLimitConfig(config)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		if err := client.Get(ctx, "/", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Get() error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("cancelled before request", func(t *testing.T) {
+		client := NewClient("http://127.0.0.1:0")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := client.Get(ctx, "/", nil, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Get() error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	t.Run("error status includes body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "user not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		err := NewClient(server.URL).Get(context.Background(), "/", nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "request failed with status 404: user not found") {
+			t.Errorf("Get() error = %v", err)
+		}
+	})
+
+	t.Run("transport error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		err := NewClient(server.URL).Get(context.Background(), "/", nil, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "error performing request:") {
+			t.Errorf("Get() error = %v", err)
+		}
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		err := NewClient("://bad").Get(context.Background(), "/", nil, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "error creating request:") {
+			t.Errorf("Get() error = %v", err)
+		}
+	})
+
+	t.Run("truncated body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("<user>"))
+		}))
+		defer server.Close()
+
+		err := NewClient(server.URL).Get(context.Background(), "/", nil, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "error reading response body:") {
+			t.Errorf("Get() error = %v", err)
+		}
+	})
+}
+
+func FuzzDecodeJSON(f *testing.F) {
+	f.Add([]byte(`{"id":1,"name":"John"}`))
+	f.Add([]byte(`[1,2,3]`))
+	f.Add([]byte(`{"id":"not a number"}`))
+	f.Add([]byte(``))
+
+	client := NewClient("http://example.com")
+	client.SetResponseType(ContentTypeJSON)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var user testUser
+		client.decode(data, &user)
+		var dynamic interface{}
+		client.decode(data, &dynamic)
+	})
+}
+
+func FuzzDecodeXML(f *testing.F) {
+	f.Add([]byte(`<user><id>1</id><name>John</name></user>`))
+	f.Add([]byte(`<?xml version="1.0"?><USER_LIST_OUTPUT/>`))
+	f.Add([]byte(`<user><id>x</id>`))
+	f.Add([]byte(``))
+
+	client := NewClient("http://example.com")
+	client.SetResponseType(ContentTypeXML)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var user testUser
+		if err := client.decode(data, &user); err != nil {
+			var decodeErr *decodeError
+			if !errors.As(err, &decodeErr) {
+				t.Errorf("decode() error %v is not a decode error", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,413 @@
+package godefaultapi
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAuditChainBroken is returned by VerifyAuditLog when a record was
+// altered, removed or inserted
+var ErrAuditChainBroken = errors.New("audit log hash chain broken")
+
+// auditBodyLimit is how much of a response body is searched for the vendor
+// response code
+const auditBodyLimit = 64 << 10
+
+// AuditRecord is one line of an audit log, describing a mutating request
+// attempt. Hash is the SHA-256 of the record with an empty Hash, and
+// PrevHash is the Hash of the record before it, so that any change to the
+// log breaks the chain.
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor,omitempty"`
+	Method string    `json:"method"`
+	// Endpoint is the request URL without its query
+	Endpoint string `json:"endpoint"`
+	// Route is the route template of the call, see WithRoute
+	Route string `json:"route,omitempty"`
+	// Params are the query and form parameters, with credentials redacted
+	Params url.Values `json:"params,omitempty"`
+	// BodySHA256 is the SHA-256 of a request body that is not a form
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	Attempt    int    `json:"attempt,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	// ResponseCode is the vendor's result code from the response body
+	ResponseCode string `json:"response_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// DryRun marks requests captured in dry-run mode
+	DryRun   bool   `json:"dry_run,omitempty"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// hash returns the hash of the record with an empty Hash
+func (r AuditRecord) hash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog is a middleware that appends a hash chained JSON-lines record of
+// every mutating request attempt to a sink
+type AuditLog struct {
+	sink         io.Writer
+	actor        string
+	isMutation   MutationFunc
+	responseCode func(body []byte) string
+	now          func() time.Time
+
+	mu       sync.Mutex
+	lastHash string
+	err      error
+}
+
+// NewAuditLog creates an audit log writing to sink, which receives one
+// complete line per Write. actor names who makes the changes; if empty the
+// basic auth username is used. When sink is an AuditFile the chain continues
+// from its last record.
+func NewAuditLog(sink io.Writer, actor string) *AuditLog {
+	a := &AuditLog{
+		sink:         sink,
+		actor:        actor,
+		isMutation:   MethodIsMutation,
+		responseCode: QualysResponseCode,
+		now:          time.Now,
+	}
+	if file, ok := sink.(*AuditFile); ok {
+		a.lastHash = file.lastHash
+	}
+	return a
+}
+
+// SetResponseCodeFunc sets how the vendor response code is found in a
+// response body. The default is QualysResponseCode.
+func (a *AuditLog) SetResponseCodeFunc(f func(body []byte) string) {
+	a.responseCode = f
+}
+
+// SetMutationFunc sets which requests are recorded, for APIs that make
+// changes with GET requests. The default is MethodIsMutation; share the
+// function with DryRun.IsMutation so both agree.
+func (a *AuditLog) SetMutationFunc(f MutationFunc) {
+	if f == nil {
+		f = MethodIsMutation
+	}
+	a.isMutation = f
+}
+
+// Err returns the first error writing to the sink. Requests are not failed
+// when their record cannot be written, since the change was already made.
+func (a *AuditLog) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// Middleware returns the middleware that writes the audit records
+func (a *AuditLog) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !a.isMutation(req) {
+				return next.RoundTrip(req)
+			}
+
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			record := a.newRecord(req, decodeRequestBody(req, body))
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				record.Error = redactError(req.Context(), err, req.URL.String())
+				a.write(record)
+				return nil, err
+			}
+			record.Status = resp.StatusCode
+			record.DryRun = resp.Header.Get(dryRunHeader) != ""
+			if a.responseCode != nil {
+				head, err := peekBody(resp, auditBodyLimit)
+				if err != nil {
+					return nil, err
+				}
+				record.ResponseCode = a.responseCode(head)
+			}
+			a.write(record)
+			return resp, nil
+		})
+	}
+}
+
+// newRecord describes a request
+func (a *AuditLog) newRecord(req *http.Request, body []byte) AuditRecord {
+	endpoint := *req.URL
+	endpoint.RawQuery = ""
+	endpoint.User = nil
+	record := AuditRecord{
+		Actor:    a.actor,
+		Method:   req.Method,
+		Endpoint: endpoint.String(),
+		Params:   redactValues(req.Context(), req.URL.Query()),
+	}
+	if record.Actor == "" {
+		record.Actor, _, _ = req.BasicAuth()
+	}
+	if info, ok := RequestInfoFromContext(req.Context()); ok {
+		record.Route = info.Route
+		record.Attempt = info.Attempt
+	}
+
+	if len(body) > 0 {
+		form, err := url.ParseQuery(string(body))
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") && err == nil {
+			for key, values := range redactValues(req.Context(), form) {
+				record.Params[key] = append(record.Params[key], values...)
+			}
+		} else {
+			sum := sha256.Sum256(body)
+			record.BodySHA256 = hex.EncodeToString(sum[:])
+		}
+	}
+	if len(record.Params) == 0 {
+		record.Params = nil
+	}
+	return record
+}
+
+// write chains and appends a record
+func (a *AuditLog) write(record AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	record.Time = a.now().UTC()
+	record.PrevHash = a.lastHash
+	hash, err := record.hash()
+	if err == nil {
+		record.Hash = hash
+		var line []byte
+		if line, err = json.Marshal(record); err == nil {
+			_, err = a.sink.Write(append(line, '\n'))
+		}
+	}
+	if err != nil {
+		if a.err == nil {
+			a.err = fmt.Errorf("error writing audit record: %w", err)
+		}
+		return
+	}
+	a.lastHash = record.Hash
+}
+
+// peekBody returns up to limit bytes of a response body without consuming it
+func peekBody(resp *http.Response, limit int64) ([]byte, error) {
+	head, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return head, nil
+}
+
+// qualysResponseCodes find the result code in the responses of the Qualys
+// APIs, in order of preference
+var qualysResponseCodes = []*regexp.Regexp{
+	regexp.MustCompile(`<responseCode>\s*([^<\s]+)\s*</responseCode>`),
+	regexp.MustCompile(`"responseCode"\s*:\s*"([^"]+)"`),
+	regexp.MustCompile(`<CODE>\s*([^<\s]+)\s*</CODE>`),
+	regexp.MustCompile(`<RETURN\b[^>]*\bnumber="([^"]+)"`),
+	regexp.MustCompile(`<RETURN\b[^>]*\bstatus="([^"]+)"`),
+}
+
+// QualysResponseCode returns the result code of a Qualys API response: the
+// QPS responseCode, the v2 error CODE, or the status of a v1 RETURN element
+func QualysResponseCode(body []byte) string {
+	for _, pattern := range qualysResponseCodes {
+		if match := pattern.FindSubmatch(body); match != nil {
+			return string(match[1])
+		}
+	}
+	return ""
+}
+
+// VerifyAuditLog checks the hash chain of an audit log. prevHash is the
+// hash of the record before the first one in r, empty for a new log. It
+// returns the hash of the last record, to verify a following rotated file.
+func VerifyAuditLog(r io.Reader, prevHash string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return "", fmt.Errorf("line %d: error decoding audit record: %w", line, err)
+		}
+		hash, err := record.hash()
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.PrevHash != prevHash || record.Hash != hash {
+			return "", fmt.Errorf("line %d: %w", line, ErrAuditChainBroken)
+		}
+		prevHash = record.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading audit log: %w", err)
+	}
+	return prevHash, nil
+}
+
+// AuditFile is an append-only audit log file that rotates when it reaches
+// a maximum size. Rotated files are renamed path.1, path.2 and so on, path.1
+// being the most recent.
+type AuditFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	lastHash string
+}
+
+// OpenAuditFile opens or creates the audit log at path. A maxSize of zero
+// never rotates. Rotated files beyond maxBackups are deleted, and a
+// maxBackups of zero keeps them all.
+func OpenAuditFile(path string, maxSize int64, maxBackups int) (*AuditFile, error) {
+	f := &AuditFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	for _, name := range []string{path, path + ".1"} {
+		hash, err := lastAuditHash(name)
+		if err != nil {
+			return nil, err
+		}
+		if hash != "" {
+			f.lastHash = hash
+			break
+		}
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p, rotating the file first if p would exceed its maximum size
+func (f *AuditFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, f.file.Sync()
+}
+
+// Close closes the file
+func (f *AuditFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the current file for appending
+func (f *AuditFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate renames the current file to path.1, shifting older files up
+func (f *AuditFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("error rotating audit log: %w", err)
+	}
+	f.file = nil
+
+	backups := 0
+	for {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", f.path, backups+1)); err != nil {
+			break
+		}
+		backups++
+	}
+	for i := backups; i >= 1; i-- {
+		name := fmt.Sprintf("%s.%d", f.path, i)
+		if f.maxBackups > 0 && i >= f.maxBackups {
+			os.Remove(name)
+			continue
+		}
+		if err := os.Rename(name, fmt.Sprintf("%s.%d", f.path, i+1)); err != nil {
+			return fmt.Errorf("error rotating audit log: %w", err)
+		}
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return fmt.Errorf("error rotating audit log: %w", err)
+	}
+	return f.open()
+}
+
+// lastAuditHash returns the hash of the last record in the audit log at
+// path, or "" if there is none
+func lastAuditHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error reading audit log: %w", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	last := lines[len(lines)-1]
+	if len(last) == 0 {
+		return "", nil
+	}
+	var record AuditRecord
+	if err := json.Unmarshal(last, &record); err != nil {
+		return "", fmt.Errorf("error decoding last audit record in %s: %w", path, err)
+	}
+	return record.Hash, nil
+}
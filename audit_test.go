@@ -0,0 +1,209 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<USER_OUTPUT><RETURN status="SUCCESS" number="0"><MESSAGE>bob added</MESSAGE></RETURN></USER_OUTPUT>`))
+	}))
+	defer server.Close()
+
+	var sink bytes.Buffer
+	audit := NewAuditLog(&sink, "")
+	client := NewClient(server.URL)
+	client.SetBasicAuth("admin", "secret")
+	client.Use(audit.Middleware())
+	ctx := context.Background()
+
+	if err := client.Get(ctx, "/msp/user_list.php", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := client.Post(ctx, "/msp/user.php?action=add&user_login=bob&password=hunter2", nil, nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	ctx = WithRoute(ctx, "/qps/rest/2.0/update/am/asset/{id}")
+	if err := client.Post(ctx, "/qps/rest/2.0/update/am/asset/42", []byte(`{"name":"web"}`), nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(sink.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit lines = %d, want 2 for the POSTs only:\n%s", len(lines), sink.String())
+	}
+	var first, second AuditRecord
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+
+	if first.Actor != "admin" || first.Method != http.MethodPost || first.Endpoint != server.URL+"/msp/user.php" {
+		t.Errorf("record = %+v", first)
+	}
+	if first.Params.Get("user_login") != "bob" || first.Params.Get("password") != redactedValue {
+		t.Errorf("params = %v, want password redacted", first.Params)
+	}
+	if first.Status != http.StatusOK || first.ResponseCode != "0" || first.Attempt != 1 {
+		t.Errorf("outcome = %d %q attempt %d", first.Status, first.ResponseCode, first.Attempt)
+	}
+	if second.Route != "/qps/rest/2.0/update/am/asset/{id}" || second.BodySHA256 == "" || second.Params != nil {
+		t.Errorf("record = %+v, want route and body hash", second)
+	}
+	if first.PrevHash != "" || second.PrevHash != first.Hash {
+		t.Errorf("chain = %q <- %q, want linked records", first.Hash, second.PrevHash)
+	}
+
+	if _, err := VerifyAuditLog(strings.NewReader(sink.String()), ""); err != nil {
+		t.Errorf("VerifyAuditLog() error = %v", err)
+	}
+	tampered := strings.Replace(sink.String(), `"user_login":["bob"]`, `"user_login":["eve"]`, 1)
+	if _, err := VerifyAuditLog(strings.NewReader(tampered), ""); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("VerifyAuditLog(tampered) error = %v, want ErrAuditChainBroken", err)
+	}
+	removed := lines[1] + "\n"
+	if _, err := VerifyAuditLog(strings.NewReader(removed), ""); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("VerifyAuditLog(removed) error = %v, want ErrAuditChainBroken", err)
+	}
+}
+
+func TestAuditLogDryRun(t *testing.T) {
+	var sink bytes.Buffer
+	client := NewClient("http://unused.invalid")
+	client.Use(NewAuditLog(&sink, "ops").Middleware())
+	client.SetDryRun(&DryRun{})
+
+	form := []byte("action=login&username=ops&password=secret")
+	ctx := withHeaders(context.Background(), http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+	if err := client.Post(ctx, "/api/2.0/fo/session/", form, nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	var record AuditRecord
+	if err := json.Unmarshal(sink.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if !record.DryRun || record.Status != http.StatusNoContent {
+		t.Errorf("record = %+v, want a dry run", record)
+	}
+	if record.Params.Get("username") != "ops" || record.Params.Get("password") != redactedValue {
+		t.Errorf("params = %v, want form parameters with password redacted", record.Params)
+	}
+}
+
+func TestAuditLogMutatingGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<USER_OUTPUT><RETURN status="SUCCESS"/></USER_OUTPUT>`))
+	}))
+	defer server.Close()
+
+	isMutation := func(req *http.Request) bool {
+		return req.URL.Path == "/msp/user.php" || MethodIsMutation(req)
+	}
+	var sink bytes.Buffer
+	audit := NewAuditLog(&sink, "ops")
+	audit.SetMutationFunc(isMutation)
+	client := NewClient(server.URL)
+	client.Use(audit.Middleware())
+	ctx := context.Background()
+
+	if err := client.Get(ctx, "/msp/user_list.php", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := client.Get(ctx, "/msp/user.php?action=add&user_login=bob", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(sink.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("audit lines = %d, want 1 for the add:\n%s", len(lines), sink.String())
+	}
+	var record AuditRecord
+	json.Unmarshal([]byte(lines[0]), &record)
+	if record.Method != http.MethodGet || record.Params.Get("action") != "add" {
+		t.Errorf("record = %+v, want the user.php add", record)
+	}
+
+	// The same function makes DryRun capture the add
+	sink.Reset()
+	dryRun := &DryRun{ExecuteReads: true, IsMutation: isMutation}
+	client.SetDryRun(dryRun)
+	if err := client.Get(ctx, "/msp/user.php?action=add&user_login=bob", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(dryRun.Requests()) != 1 || !strings.Contains(sink.String(), `"dry_run":true`) {
+		t.Errorf("captured %d requests, audit = %s", len(dryRun.Requests()), sink.String())
+	}
+}
+
+func TestQualysResponseCode(t *testing.T) {
+	tests := map[string]string{
+		`<ServiceResponse><responseCode>SUCCESS</responseCode></ServiceResponse>`:        "SUCCESS",
+		`{"ServiceResponse":{"responseCode":"INVALID_REQUEST"}}`:                         "INVALID_REQUEST",
+		`<SIMPLE_RETURN><RESPONSE><CODE>1905</CODE><TEXT>denied</TEXT></RESPONSE>`:       "1905",
+		`<USER_OUTPUT><RETURN status="FAILED" number="2003"><MESSAGE/></RETURN>`:         "2003",
+		`<USER_OUTPUT><RETURN status="SUCCESS"><MESSAGE>added</MESSAGE></RETURN>`:        "SUCCESS",
+		`<SIMPLE_RETURN><RESPONSE><TEXT>New IPs added</TEXT></RESPONSE></SIMPLE_RETURN>`: "",
+	}
+	for body, want := range tests {
+		if got := QualysResponseCode([]byte(body)); got != want {
+			t.Errorf("QualysResponseCode(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestAuditFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	file, err := OpenAuditFile(path, 600, 2)
+	if err != nil {
+		t.Fatalf("OpenAuditFile() error = %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := NewClient(server.URL)
+	client.Use(NewAuditLog(file, "ops").Middleware())
+	for i := 0; i < 3; i++ {
+		if err := client.Post(context.Background(), "/tags", nil, nil); err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+	}
+	file.Close()
+
+	// Reopening continues the chain across rotated files
+	file, err = OpenAuditFile(path, 600, 2)
+	if err != nil {
+		t.Fatalf("OpenAuditFile() error = %v", err)
+	}
+	client = NewClient(server.URL)
+	client.Use(NewAuditLog(file, "ops").Middleware())
+	if err := client.Post(context.Background(), "/tags", nil, nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	file.Close()
+
+	var hash string
+	records := 0
+	for _, name := range []string{path + ".2", path + ".1", path} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("rotated file missing: %v", err)
+		}
+		records += strings.Count(string(data), "\n")
+		if hash, err = VerifyAuditLog(bytes.NewReader(data), hash); err != nil {
+			t.Fatalf("VerifyAuditLog(%s) error = %v", filepath.Base(name), err)
+		}
+	}
+	if records != 4 {
+		t.Errorf("records = %d, want 4", records)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists, want at most 2 backups", path)
+	}
+}
@@ -0,0 +1,142 @@
+package godefaultapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Authenticator adds authentication to an outgoing request. Authenticate is
+// called for every attempt, including retries, with the exact body bytes sent.
+type Authenticator interface {
+	Authenticate(req *http.Request, body []byte) error
+}
+
+// AuthenticatorFunc adapts an ordinary function to the Authenticator interface
+type AuthenticatorFunc func(req *http.Request, body []byte) error
+
+// Authenticate calls f(req, body)
+func (f AuthenticatorFunc) Authenticate(req *http.Request, body []byte) error {
+	return f(req, body)
+}
+
+// APIKeyAuth sends a static API key in a header or query parameter
+type APIKeyAuth struct {
+	// Key is the API key value
+	Key string
+	// Header is the header to send the key in, e.g. "X-API-Key"
+	Header string
+	// QueryParam is the query parameter to send the key in, used when Header is empty
+	QueryParam string
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuth) Authenticate(req *http.Request, body []byte) error {
+	switch {
+	case a.Header != "":
+		req.Header.Set(a.Header, a.Key)
+	case a.QueryParam != "":
+		query := req.URL.Query()
+		query.Set(a.QueryParam, a.Key)
+		req.URL.RawQuery = query.Encode()
+	default:
+		return fmt.Errorf("api key auth requires a header or query parameter name")
+	}
+	return nil
+}
+
+// CredentialNames implements CredentialReporter
+func (a *APIKeyAuth) CredentialNames() (headers, queryParams []string) {
+	if a.Header != "" {
+		return []string{a.Header}, nil
+	}
+	if a.QueryParam != "" {
+		return nil, []string{a.QueryParam}
+	}
+	return nil, nil
+}
+
+// HMACSigner signs requests with HMAC-SHA256. The signed string is the
+// method, request URI, unix timestamp and hex SHA-256 of the body, joined
+// by newlines. The signature is sent hex encoded.
+type HMACSigner struct {
+	// KeyID identifies the secret to the server, sent in KeyIDHeader if set
+	KeyID string
+	// Secret is the shared HMAC key
+	Secret []byte
+	// SignatureHeader is the header carrying the signature (default "X-Signature")
+	SignatureHeader string
+	// TimestampHeader is the header carrying the timestamp (default "X-Timestamp")
+	TimestampHeader string
+	// KeyIDHeader is the header carrying KeyID (default "X-Key-Id")
+	KeyIDHeader string
+	// Now returns the signing time, defaults to time.Now
+	Now func() time.Time
+}
+
+// NewHMACSigner creates an HMAC signer with default header names
+func NewHMACSigner(keyID string, secret []byte) *HMACSigner {
+	return &HMACSigner{
+		KeyID:           keyID,
+		Secret:          secret,
+		SignatureHeader: "X-Signature",
+		TimestampHeader: "X-Timestamp",
+		KeyIDHeader:     "X-Key-Id",
+	}
+}
+
+// Authenticate implements Authenticator
+func (s *HMACSigner) Authenticate(req *http.Request, body []byte) error {
+	if len(s.Secret) == 0 {
+		return fmt.Errorf("hmac signer requires a secret")
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+
+	signature := s.Sign(req.Method, req.URL.RequestURI(), timestamp, body)
+
+	req.Header.Set(headerOrDefault(s.TimestampHeader, "X-Timestamp"), timestamp)
+	req.Header.Set(headerOrDefault(s.SignatureHeader, "X-Signature"), signature)
+	if s.KeyID != "" {
+		req.Header.Set(headerOrDefault(s.KeyIDHeader, "X-Key-Id"), s.KeyID)
+	}
+	return nil
+}
+
+// CredentialNames implements CredentialReporter
+func (s *HMACSigner) CredentialNames() (headers, queryParams []string) {
+	return []string{headerOrDefault(s.SignatureHeader, "X-Signature")}, nil
+}
+
+// cacheScope implements cacheScoper, since the signature changes with every
+// request
+func (s *HMACSigner) cacheScope() string {
+	secret := sha256.Sum256(s.Secret)
+	return s.KeyID + ":" + hex.EncodeToString(secret[:])
+}
+
+// Sign returns the hex encoded signature for the given request components
+func (s *HMACSigner) Sign(method, requestURI, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	stringToSign := method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])
+
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// headerOrDefault returns name, or def if name is empty
+func headerOrDefault(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
@@ -0,0 +1,134 @@
+package godefaultapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHMACSignerKnownAnswers(t *testing.T) {
+	signer := NewHMACSigner("key-1", []byte("secret-key"))
+
+	tests := []struct {
+		name       string
+		method     string
+		requestURI string
+		body       []byte
+		want       string
+	}{
+		{
+			name:       "post with body and query",
+			method:     http.MethodPost,
+			requestURI: "/v1/items?limit=10",
+			body:       []byte(`{"name":"test"}`),
+			want:       "064d438beb4361cbd509272bbf0a31dea7c330c2999380c8a27d21e3ba977399",
+		},
+		{
+			name:       "get without body",
+			method:     http.MethodGet,
+			requestURI: "/v1/items",
+			body:       nil,
+			want:       "9bf2c8e3724eb23a82c6f9203faedc9319f12d481774c223fe0b684a72dfa46b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := signer.Sign(tt.method, tt.requestURI, "1700000000", tt.body)
+			if got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHMACSignerSignsEveryAttempt(t *testing.T) {
+	signer := NewHMACSigner("key-1", []byte("secret-key"))
+	body := []byte(`{"name":"test"}`)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		got, _ := io.ReadAll(r.Body)
+		if string(got) != string(body) {
+			t.Errorf("attempt %d: body = %q, want %q", attempts, got, body)
+		}
+		want := signer.Sign(r.Method, r.URL.RequestURI(), r.Header.Get("X-Timestamp"), got)
+		if sig := r.Header.Get("X-Signature"); sig != want {
+			t.Errorf("attempt %d: signature = %s, want %s", attempts, sig, want)
+		}
+		if r.Header.Get("X-Key-Id") != "key-1" {
+			t.Errorf("attempt %d: missing key id header", attempts)
+		}
+		if attempts == 1 {
+			w.Header().Set("X-RateLimit-Reset", "soon")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetRateLimitConfig(&RateLimitConfig{
+		HeaderName:      "X-RateLimit-Reset",
+		MaxRetries:      3,
+		DefaultWaitTime: time.Millisecond,
+	})
+	client.SetAuthenticator(signer)
+
+	var result map[string]interface{}
+	if err := client.Post(context.Background(), "/v1/items?limit=10", body, &result); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      *APIKeyAuth
+		wantQuery string
+		wantKey   string
+	}{
+		{
+			name:    "header",
+			auth:    &APIKeyAuth{Key: "abc123", Header: "X-API-Key"},
+			wantKey: "abc123",
+		},
+		{
+			name:      "query parameter",
+			auth:      &APIKeyAuth{Key: "abc123", QueryParam: "api_key"},
+			wantQuery: "abc123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/items?limit=10", nil)
+			if err := tt.auth.Authenticate(req, nil); err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if got := req.Header.Get("X-API-Key"); got != tt.wantKey {
+				t.Errorf("header = %q, want %q", got, tt.wantKey)
+			}
+			if got := req.URL.Query().Get("api_key"); got != tt.wantQuery {
+				t.Errorf("query = %q, want %q", got, tt.wantQuery)
+			}
+			if got := req.URL.Query().Get("limit"); got != "10" {
+				t.Errorf("existing query parameter lost, limit = %q", got)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := (&APIKeyAuth{Key: "abc123"}).Authenticate(req, nil); err == nil {
+		t.Error("Authenticate() with no header or query parameter should fail")
+	}
+}
@@ -0,0 +1,223 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BatchRequest describes a single request in a batch
+type BatchRequest struct {
+	// Method is the HTTP method, GET if empty
+	Method string
+	// Path is the request path relative to the client's base URL
+	Path string
+	// Body is the request body, if any
+	Body []byte
+	// Result is decoded into from the response, if not nil
+	Result interface{}
+}
+
+// BatchResult is the outcome of a single batch request
+type BatchResult struct {
+	// Index is the position of the request in the input
+	Index int
+	// Request is the request that was run
+	Request BatchRequest
+	// Err is the error returned for the request, if any
+	Err error
+	// Duration is how long the request took
+	Duration time.Duration
+}
+
+// BatchResults are batch results ordered by input position
+type BatchResults []BatchResult
+
+// Err returns all request errors joined together, or nil if every request succeeded
+func (r BatchResults) Err() error {
+	var errs []error
+	for _, result := range r {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("request %d (%s): %w", result.Index, result.Request.Path, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Batch runs many requests through a bounded worker pool on a shared client
+type Batch struct {
+	client      *Client
+	concurrency int
+	interval    time.Duration
+	onProgress  func(completed, total int, result BatchResult)
+	progress    ProgressReporter
+}
+
+// NewBatch creates a batch runner for client with a concurrency of 4
+func NewBatch(client *Client) *Batch {
+	return &Batch{
+		client:      client,
+		concurrency: 4,
+		progress:    NopProgressReporter{},
+	}
+}
+
+// SetConcurrency sets the number of requests run at once
+func (b *Batch) SetConcurrency(n int) {
+	b.concurrency = max(n, 1)
+}
+
+// SetInterval sets the minimum time between the start of consecutive
+// requests across all workers, to stay within API rate limits
+func (b *Batch) SetInterval(interval time.Duration) {
+	b.interval = interval
+}
+
+// OnProgress sets a callback invoked after each request completes. total is
+// -1 when running from a channel. Callbacks are not run concurrently.
+func (b *Batch) OnProgress(fn func(completed, total int, result BatchResult)) {
+	b.onProgress = fn
+}
+
+// SetProgressReporter sets the reporter that is advanced by one for every
+// completed request. A nil reporter disables reporting.
+func (b *Batch) SetProgressReporter(reporter ProgressReporter) {
+	if reporter == nil {
+		reporter = NopProgressReporter{}
+	}
+	b.progress = reporter
+}
+
+// Run runs requests and returns their results in input order. Requests not
+// started before ctx is cancelled fail with the context's error.
+func (b *Batch) Run(ctx context.Context, requests []BatchRequest) BatchResults {
+	ch := make(chan BatchRequest)
+	go func() {
+		defer close(ch)
+		for _, req := range requests {
+			select {
+			case ch <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := b.run(ctx, ch, len(requests))
+	for i := len(results); i < len(requests); i++ {
+		results = append(results, BatchResult{Index: i, Request: requests[i], Err: ctx.Err()})
+	}
+	return results
+}
+
+// RunChan runs requests received from ch until it is closed or ctx is
+// cancelled, and returns their results in the order they were received
+func (b *Batch) RunChan(ctx context.Context, ch <-chan BatchRequest) BatchResults {
+	return b.run(ctx, ch, -1)
+}
+
+// run is the worker pool shared by Run and RunChan
+func (b *Batch) run(ctx context.Context, ch <-chan BatchRequest, total int) BatchResults {
+	type job struct {
+		index int
+		req   BatchRequest
+	}
+
+	b.progress.Start(int64(total))
+	defer b.progress.Finish()
+
+	jobs := make(chan job)
+	var (
+		mu        sync.Mutex
+		results   BatchResults
+		completed int
+		wg        sync.WaitGroup
+	)
+
+	var ticker *time.Ticker
+	if b.interval > 0 {
+		ticker = time.NewTicker(b.interval)
+		defer ticker.Stop()
+	}
+
+	for i := 0; i < max(b.concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := b.do(ctx, j.index, j.req)
+
+				mu.Lock()
+				results[j.index] = result
+				completed++
+				b.progress.Advance(1)
+				if b.onProgress != nil {
+					b.onProgress(completed, total, result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	index := 0
+	first := true
+feed:
+	for {
+		var req BatchRequest
+		var ok bool
+		select {
+		case req, ok = <-ch:
+			if !ok {
+				break feed
+			}
+		case <-ctx.Done():
+			break feed
+		}
+
+		if ticker != nil && !first {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				mu.Lock()
+				results = append(results, BatchResult{Index: index, Request: req, Err: ctx.Err()})
+				mu.Unlock()
+				break feed
+			}
+		}
+		first = false
+
+		mu.Lock()
+		results = append(results, BatchResult{Index: index, Request: req})
+		mu.Unlock()
+		jobs <- job{index: index, req: req}
+		index++
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// do runs a single batch request
+func (b *Batch) do(ctx context.Context, index int, req BatchRequest) BatchResult {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	start := time.Now()
+	var body interface{}
+	if req.Body != nil {
+		body = req.Body
+	}
+	err := b.client.Do(ctx, method, req.Path, body, req.Result)
+	return BatchResult{
+		Index:    index,
+		Request:  req,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+}
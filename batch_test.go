@@ -0,0 +1,145 @@
+package godefaultapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchRun(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Query().Get("id") == "3" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"user-%s"}`, r.URL.Query().Get("id"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+
+	items := make([]cacheItem, 8)
+	requests := make([]BatchRequest, len(items))
+	for i := range requests {
+		requests[i] = BatchRequest{
+			Method: http.MethodPost,
+			Path:   fmt.Sprintf("/msp/user.php?id=%d", i),
+			Result: &items[i],
+		}
+	}
+
+	var progress []int
+	batch := NewBatch(client)
+	batch.SetConcurrency(2)
+	batch.OnProgress(func(completed, total int, result BatchResult) {
+		if total != len(requests) {
+			t.Errorf("progress total = %d, want %d", total, len(requests))
+		}
+		progress = append(progress, completed)
+	})
+
+	results := batch.Run(context.Background(), requests)
+	if len(results) != len(requests) {
+		t.Fatalf("got %d results, want %d", len(results), len(requests))
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if i == 3 {
+			if result.Err == nil {
+				t.Error("request 3 should fail")
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Errorf("request %d error = %v", i, result.Err)
+		}
+		if want := fmt.Sprintf("user-%d", i); items[i].Name != want {
+			t.Errorf("item %d name = %q, want %q", i, items[i].Name, want)
+		}
+	}
+
+	if err := results.Err(); err == nil || !strings.Contains(err.Error(), "request 3") {
+		t.Errorf("Err() = %v, want an error for request 3", err)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+		t.Errorf("max in flight = %d, want at most 2", got)
+	}
+	if len(progress) != len(requests) || progress[len(progress)-1] != len(requests) {
+		t.Errorf("progress = %v", progress)
+	}
+}
+
+func TestBatchRunChanCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan BatchRequest)
+	go func() {
+		ch <- BatchRequest{Path: "/a"}
+		ch <- BatchRequest{Path: "/b"}
+		cancel()
+	}()
+
+	batch := NewBatch(client)
+	batch.SetInterval(time.Millisecond)
+	results := batch.RunChan(ctx, ch)
+	if len(results) < 1 || len(results) > 2 {
+		t.Fatalf("got %d results, want 1 or 2", len(results))
+	}
+	if results[0].Request.Path != "/a" {
+		t.Errorf("first result path = %q, want /a", results[0].Request.Path)
+	}
+}
+
+type recordingProgress struct {
+	started, finished bool
+	total, advanced   int64
+}
+
+func (p *recordingProgress) Start(total int64)    { p.started, p.total = true, total }
+func (p *recordingProgress) SetTotal(total int64) { p.total = total }
+func (p *recordingProgress) Advance(n int64)      { p.advanced += n }
+func (p *recordingProgress) Finish()              { p.finished = true }
+
+func TestBatchProgressReporter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+
+	progress := &recordingProgress{}
+	batch := NewBatch(client)
+	batch.SetProgressReporter(progress)
+	batch.Run(context.Background(), []BatchRequest{{Path: "/a"}, {Path: "/b"}, {Path: "/c"}})
+
+	if !progress.started || !progress.finished || progress.total != 3 || progress.advanced != 3 {
+		t.Errorf("progress = %+v", progress)
+	}
+}
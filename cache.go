@@ -0,0 +1,367 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a stored GET response
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+	// Expires is when the response stops being fresh. A zero value means
+	// the response must be revalidated before use.
+	Expires time.Time `json:"expires"`
+}
+
+// CacheStore is a cache storage backend
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, entry *CachedResponse) error
+	Delete(key string) error
+}
+
+// Cache stores GET responses and revalidates them with ETag and
+// Last-Modified. Cache-Control no-store, no-cache and max-age and the
+// Expires header are honored. Range requests and requests sent with
+// Cache-Control no-store bypass the cache.
+type Cache struct {
+	store      CacheStore
+	defaultTTL time.Duration
+	now        func() time.Time
+}
+
+// NewCache creates a cache backed by store. Responses without freshness
+// information are revalidated on every use.
+func NewCache(store CacheStore) *Cache {
+	return &Cache{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// SetDefaultTTL sets how long responses without Cache-Control max-age or
+// Expires headers stay fresh
+func (c *Cache) SetDefaultTTL(ttl time.Duration) {
+	c.defaultTTL = ttl
+}
+
+// SetCache enables response caching for GET requests. A nil cache disables it.
+func (c *Client) SetCache(cache *Cache) {
+	c.cache = cache
+}
+
+// cacheTTLKey is the context key for a per-request cache TTL
+type cacheTTLKey struct{}
+
+// WithCacheTTL returns a context whose GET requests treat cached responses
+// as fresh for ttl, overriding the response's Cache-Control headers. A ttl of
+// zero forces revalidation.
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, cacheTTLKey{}, ttl)
+}
+
+// cacheHeader marks responses served from the cache
+const cacheHeader = "X-From-Cache"
+
+// middleware returns the caching transport. stripHeader is removed from
+// stored responses so that cached copies never trigger rate limit retries.
+func (c *Cache) middleware(stripHeader string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := cacheKey(req)
+			if req.Method != http.MethodGet {
+				resp, err := next.RoundTrip(req)
+				if err == nil && resp.StatusCode < 400 {
+					c.store.Delete(key)
+				}
+				return resp, err
+			}
+			if req.Header.Get("Range") != "" || hasCacheDirective(req.Header, "no-store") {
+				return next.RoundTrip(req)
+			}
+
+			entry, cached := c.store.Get(key)
+			if cached && c.fresh(req.Context(), entry) {
+				return entry.response(req), nil
+			}
+
+			if cached {
+				req = req.Clone(req.Context())
+				if etag := entry.Header.Get("ETag"); etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+					req.Header.Set("If-Modified-Since", lastModified)
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if resp.StatusCode == http.StatusNotModified && cached {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				for key, values := range resp.Header {
+					entry.Header[key] = values
+				}
+				entry.Header.Del(stripHeader)
+				entry.StoredAt = c.now()
+				entry.Expires = c.expires(resp.Header, entry.StoredAt)
+				c.store.Set(key, entry)
+				return entry.response(req), nil
+			}
+
+			if resp.StatusCode != http.StatusOK || hasCacheDirective(resp.Header, "no-store") {
+				return resp, nil
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+
+			header := resp.Header.Clone()
+			header.Del(stripHeader)
+			storedAt := c.now()
+			c.store.Set(key, &CachedResponse{
+				StatusCode: resp.StatusCode,
+				Header:     header,
+				Body:       body,
+				StoredAt:   storedAt,
+				Expires:    c.expires(resp.Header, storedAt),
+			})
+			return resp, nil
+		})
+	}
+}
+
+// fresh reports whether entry can be used without revalidation
+func (c *Cache) fresh(ctx context.Context, entry *CachedResponse) bool {
+	if ttl, ok := ctx.Value(cacheTTLKey{}).(time.Duration); ok {
+		return ttl > 0 && c.now().Before(entry.StoredAt.Add(ttl))
+	}
+	return !entry.Expires.IsZero() && c.now().Before(entry.Expires)
+}
+
+// expires computes when a response stored at storedAt stops being fresh
+func (c *Cache) expires(header http.Header, storedAt time.Time) time.Time {
+	if hasCacheDirective(header, "no-cache") {
+		return time.Time{}
+	}
+	if maxAge, ok := cacheDirectiveValue(header, "max-age"); ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds <= 0 {
+			return time.Time{}
+		}
+		return storedAt.Add(time.Duration(seconds) * time.Second)
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	if c.defaultTTL > 0 {
+		return storedAt.Add(c.defaultTTL)
+	}
+	return time.Time{}
+}
+
+// response builds an HTTP response from the cached entry
+func (e *CachedResponse) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set(cacheHeader, "1")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheScoper is an Authenticator whose credential headers change with every
+// request, such as a signature, and that identifies its user to the cache
+// instead
+type cacheScoper interface {
+	cacheScope() string
+}
+
+// cacheKey identifies a cached response. Credentials, including those the
+// authenticator reports, are part of the key so that responses are never
+// shared between users.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String()+"\n")
+	io.WriteString(h, req.Header.Get("Accept")+"\n")
+	io.WriteString(h, req.Header.Get("Authorization")+"\n")
+	io.WriteString(h, req.Header.Get("Cookie")+"\n")
+
+	names, _ := req.Context().Value(credentialNamesKey{}).(credentialNames)
+	if names.scope != "" {
+		io.WriteString(h, names.scope)
+	} else {
+		for _, name := range slices.Sorted(maps.Keys(names.headers)) {
+			io.WriteString(h, name+": "+strings.Join(req.Header.Values(name), ",")+"\n")
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hasCacheDirective reports whether the Cache-Control header contains directive
+func hasCacheDirective(header http.Header, directive string) bool {
+	_, ok := cacheDirectiveValue(header, directive)
+	return ok
+}
+
+// cacheDirectiveValue returns the value of a Cache-Control directive
+func cacheDirectiveValue(header http.Header, directive string) (string, bool) {
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if strings.EqualFold(name, directive) {
+				return strings.Trim(val, `"`), true
+			}
+		}
+	}
+	return "", false
+}
+
+// MemoryCacheStore keeps cached responses in memory
+type MemoryCacheStore struct {
+	mu      sync.RWMutex
+	entries map[string]*CachedResponse
+}
+
+// NewMemoryCacheStore creates an empty in-memory cache store
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: make(map[string]*CachedResponse)}
+}
+
+// Get implements CacheStore
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	copied := *entry
+	copied.Header = entry.Header.Clone()
+	return &copied, true
+}
+
+// Set implements CacheStore
+func (s *MemoryCacheStore) Set(key string, entry *CachedResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	return nil
+}
+
+// Delete implements CacheStore
+func (s *MemoryCacheStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// DiskCacheStore keeps cached responses as JSON files in a directory
+type DiskCacheStore struct {
+	dir string
+}
+
+// NewDiskCacheStore creates a cache store in dir, creating it if needed
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+// Get implements CacheStore
+func (s *DiskCacheStore) Get(key string) (*CachedResponse, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if entry.Header == nil {
+		entry.Header = make(http.Header)
+	}
+	return &entry, true
+}
+
+// Set implements CacheStore
+func (s *DiskCacheStore) Set(key string, entry *CachedResponse) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry: %w", err)
+	}
+	return writeFileAtomic(s.path(key), data, 0o600)
+}
+
+// Delete implements CacheStore
+func (s *DiskCacheStore) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file holding key
+func (s *DiskCacheStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// writeFileAtomic writes data to a temp file and renames it over path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,186 @@
+package godefaultapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type cacheItem struct {
+	Name string `json:"name"`
+}
+
+func newCacheTestServer(t *testing.T, cacheControl string) (*httptest.Server, *int, *int) {
+	t.Helper()
+	hits, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Write([]byte(`{"name":"groups"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits, &notModified
+}
+
+func TestCacheRevalidatesWithETag(t *testing.T) {
+	server, hits, notModified := newCacheTestServer(t, "")
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetCache(NewCache(NewMemoryCacheStore()))
+
+	for i := 0; i < 2; i++ {
+		var item cacheItem
+		if err := client.Get(context.Background(), "/api/2.0/fo/asset/group/?action=list", nil, &item); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if item.Name != "groups" {
+			t.Errorf("call %d: name = %q, want groups", i+1, item.Name)
+		}
+	}
+	if *hits != 2 || *notModified != 1 {
+		t.Errorf("hits = %d, not modified = %d, want 2 and 1", *hits, *notModified)
+	}
+}
+
+func TestCacheHonorsMaxAge(t *testing.T) {
+	server, hits, _ := newCacheTestServer(t, "max-age=60")
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetCache(NewCache(NewMemoryCacheStore()))
+
+	for i := 0; i < 3; i++ {
+		var item cacheItem
+		if err := client.Get(context.Background(), "/list", nil, &item); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if *hits != 1 {
+		t.Errorf("hits = %d, want 1", *hits)
+	}
+
+	// A zero TTL override forces revalidation
+	var item cacheItem
+	if err := client.Get(WithCacheTTL(context.Background(), 0), "/list", nil, &item); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if *hits != 2 || item.Name != "groups" {
+		t.Errorf("hits = %d, name = %q after forced revalidation", *hits, item.Name)
+	}
+}
+
+func TestCacheSeparatesAuthenticators(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		name := r.Header.Get("X-Auth-Token") + r.Header.Get("X-Key-Id")
+		w.Write([]byte(`{"name":"` + name + `"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		alice, bob   Authenticator
+		wantRequests int
+	}{
+		{
+			name:         "api keys",
+			alice:        &APIKeyAuth{Key: "alice", Header: "X-Auth-Token"},
+			bob:          &APIKeyAuth{Key: "bob", Header: "X-Auth-Token"},
+			wantRequests: 2,
+		},
+		{
+			name:         "request signers",
+			alice:        NewHMACSigner("alice", []byte("alice-secret")),
+			bob:          NewHMACSigner("bob", []byte("bob-secret")),
+			wantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits = 0
+			cache := NewCache(NewMemoryCacheStore())
+			for i := 0; i < 2; i++ {
+				for name, auth := range map[string]Authenticator{"alice": tt.alice, "bob": tt.bob} {
+					client := NewClient(server.URL)
+					client.SetResponseType(ContentTypeJSON)
+					client.SetAuthenticator(auth)
+					client.SetCache(cache)
+
+					var item cacheItem
+					if err := client.Get(context.Background(), "/v1/items", nil, &item); err != nil {
+						t.Fatalf("Get() error = %v", err)
+					}
+					if item.Name != name {
+						t.Errorf("%s got %q's response", name, item.Name)
+					}
+				}
+			}
+			// Repeated calls by the same user are served from the cache
+			if hits != tt.wantRequests {
+				t.Errorf("server hits = %d, want %d", hits, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestCacheNoStore(t *testing.T) {
+	server, hits, notModified := newCacheTestServer(t, "no-store")
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetCache(NewCache(NewMemoryCacheStore()))
+
+	for i := 0; i < 2; i++ {
+		var item cacheItem
+		if err := client.Get(WithCacheTTL(context.Background(), time.Hour), "/list", nil, &item); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if *hits != 2 || *notModified != 0 {
+		t.Errorf("hits = %d, not modified = %d, want 2 and 0", *hits, *notModified)
+	}
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	store, err := NewDiskCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskCacheStore() error = %v", err)
+	}
+
+	entry := &CachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"v1"`}},
+		Body:       []byte("<xml/>"),
+		StoredAt:   time.Now().Truncate(time.Second),
+	}
+	if err := store.Set("key", entry); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	got, ok := store.Get("key")
+	if !ok {
+		t.Fatal("Get() found no entry")
+	}
+	if string(got.Body) != "<xml/>" || got.Header.Get("ETag") != `"v1"` || !got.StoredAt.Equal(entry.StoredAt) {
+		t.Errorf("Get() = %+v", got)
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := store.Get("key"); ok {
+		t.Error("Get() after Delete() found an entry")
+	}
+}
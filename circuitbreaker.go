@@ -0,0 +1,276 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while a circuit breaker is rejecting requests
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the cool-down has elapsed
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through
+	CircuitHalfOpen
+)
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitScope selects which requests share a circuit breaker
+type CircuitScope int
+
+const (
+	// CircuitScopeHost uses one breaker per host
+	CircuitScopeHost CircuitScope = iota
+	// CircuitScopeRoute uses one breaker per host, method and route template
+	CircuitScopeRoute
+)
+
+// CircuitBreakerConfig holds circuit breaker configuration
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the failure rate, between 0 and 1, that opens the circuit
+	FailureThreshold float64
+	// MinRequests is the number of requests in a window before the failure rate is evaluated
+	MinRequests int
+	// Window is the period over which failures are counted
+	Window time.Duration
+	// CoolDown is how long the circuit stays open before trial requests are allowed
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of successful trial requests needed to close the circuit
+	HalfOpenRequests int
+	// Scope selects which requests share a breaker
+	Scope CircuitScope
+	// IsFailure classifies the outcome of a request. By default transport
+	// errors and 5xx responses are failures. Requests cancelled by their
+	// context are not counted either way.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called whenever a breaker changes state
+	OnStateChange func(name string, from, to CircuitState)
+}
+
+// DefaultCircuitBreakerConfig returns a default circuit breaker configuration
+func DefaultCircuitBreakerConfig() *CircuitBreakerConfig {
+	return &CircuitBreakerConfig{
+		FailureThreshold: 0.5,
+		MinRequests:      10,
+		Window:           time.Minute,
+		CoolDown:         30 * time.Second,
+		HalfOpenRequests: 1,
+		Scope:            CircuitScopeHost,
+	}
+}
+
+// CircuitBreaker stops sending requests to a failing API and fails fast
+// with ErrCircuitOpen until it has had time to recover
+type CircuitBreaker struct {
+	config   *CircuitBreakerConfig
+	now      func() time.Time
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// breaker is the state of a single circuit
+type breaker struct {
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+// NewCircuitBreaker creates a circuit breaker. A nil config uses the defaults.
+func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
+	if config == nil {
+		config = DefaultCircuitBreakerConfig()
+	}
+	return &CircuitBreaker{
+		config:   config,
+		now:      time.Now,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// State returns the current state of the named breaker. Names are hosts, or
+// "host METHOD route" when scoped per route.
+func (cb *CircuitBreaker) State(name string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if b, ok := cb.breakers[name]; ok {
+		return b.state
+	}
+	return CircuitClosed
+}
+
+// Middleware returns the middleware that applies the circuit breaker
+func (cb *CircuitBreaker) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			name := cb.name(req)
+			if !cb.allow(name) {
+				return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, name)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				// The server never answered, so the outcome says nothing about it
+				cb.release(name)
+				return resp, err
+			}
+			cb.record(name, cb.isFailure(resp, err))
+			return resp, err
+		})
+	}
+}
+
+// name returns the breaker name for a request
+func (cb *CircuitBreaker) name(req *http.Request) string {
+	if cb.config.Scope != CircuitScopeRoute {
+		return req.URL.Host
+	}
+	route := routeFor(req.Context(), req.URL.Path)
+	if info, ok := RequestInfoFromContext(req.Context()); ok {
+		route = info.Route
+	}
+	return req.URL.Host + " " + req.Method + " " + route
+}
+
+// isFailure classifies the outcome of a request
+func (cb *CircuitBreaker) isFailure(resp *http.Response, err error) bool {
+	if cb.config.IsFailure != nil {
+		return cb.config.IsFailure(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// allow reports whether a request may be sent
+func (cb *CircuitBreaker) allow(name string) bool {
+	cb.mu.Lock()
+	b := cb.breaker(name)
+	from := b.state
+	allowed := true
+
+	switch b.state {
+	case CircuitOpen:
+		if cb.now().Sub(b.openedAt) < cb.config.CoolDown {
+			allowed = false
+			break
+		}
+		b.state = CircuitHalfOpen
+		b.trials = 1
+		b.successes = 0
+	case CircuitHalfOpen:
+		if b.trials >= max(cb.config.HalfOpenRequests, 1) {
+			allowed = false
+			break
+		}
+		b.trials++
+	}
+	to := b.state
+	cb.mu.Unlock()
+
+	cb.notify(name, from, to)
+	return allowed
+}
+
+// record records the outcome of a request
+func (cb *CircuitBreaker) record(name string, failed bool) {
+	cb.mu.Lock()
+	b := cb.breaker(name)
+	from := b.state
+	now := cb.now()
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if failed {
+			b.open(now)
+			break
+		}
+		b.successes++
+		if b.successes >= max(cb.config.HalfOpenRequests, 1) {
+			b.reset(now)
+		}
+	case CircuitClosed:
+		if now.Sub(b.windowStart) > cb.config.Window {
+			b.reset(now)
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= cb.config.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= cb.config.FailureThreshold {
+			b.open(now)
+		}
+	}
+	to := b.state
+	cb.mu.Unlock()
+
+	cb.notify(name, from, to)
+}
+
+// release frees the trial slot of a request whose outcome is not recorded
+func (cb *CircuitBreaker) release(name string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if b := cb.breaker(name); b.state == CircuitHalfOpen && b.trials > 0 {
+		b.trials--
+	}
+}
+
+// breaker returns the named breaker, creating it if needed. cb.mu must be held.
+func (cb *CircuitBreaker) breaker(name string) *breaker {
+	b, ok := cb.breakers[name]
+	if !ok {
+		b = &breaker{windowStart: cb.now()}
+		cb.breakers[name] = b
+	}
+	return b
+}
+
+// notify calls the state change callback if the state changed
+func (cb *CircuitBreaker) notify(name string, from, to CircuitState) {
+	if from != to && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(name, from, to)
+	}
+}
+
+// open trips the breaker
+func (b *breaker) open(now time.Time) {
+	b.state = CircuitOpen
+	b.openedAt = now
+}
+
+// reset closes the breaker and starts a new window
+func (b *breaker) reset(now time.Time) {
+	b.state = CircuitClosed
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
@@ -0,0 +1,157 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	failing := true
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	var changes []string
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 0.5,
+		MinRequests:      3,
+		Window:           time.Minute,
+		CoolDown:         10 * time.Second,
+		HalfOpenRequests: 1,
+		OnStateChange: func(name string, from, to CircuitState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	breaker.now = func() time.Time { return now }
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.Use(breaker.Middleware())
+
+	get := func() error {
+		var result map[string]interface{}
+		return client.Get(context.Background(), "/msp/user.php", nil, &result)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := get(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: error = %v, want a status error", i+1, err)
+		}
+	}
+
+	host := mustHost(t, server.URL)
+	if got := breaker.State(host); got != CircuitOpen {
+		t.Fatalf("state = %s, want open", got)
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if hits != 3 {
+		t.Errorf("hits = %d, want 3 while open", hits)
+	}
+
+	// After the cool-down a trial request is let through and closes the circuit
+	failing = false
+	now = now.Add(11 * time.Second)
+	if err := get(); err != nil {
+		t.Fatalf("trial request error = %v", err)
+	}
+	if got := breaker.State(host); got != CircuitClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state change %d = %s, want %s", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreakerScopePerRoute(t *testing.T) {
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 1,
+		MinRequests:      1,
+		Window:           time.Minute,
+		CoolDown:         time.Minute,
+		Scope:            CircuitScopeRoute,
+	})
+	failed := httptest.NewRequest(http.MethodGet, "http://example.com/a?x=1", nil)
+	other := httptest.NewRequest(http.MethodGet, "http://example.com/b", nil)
+
+	breaker.record(breaker.name(failed), true)
+	if got := breaker.State("example.com GET /a"); got != CircuitOpen {
+		t.Errorf("state of failed route = %s, want open", got)
+	}
+	if !breaker.allow(breaker.name(other)) {
+		t.Error("other route should not be affected")
+	}
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 0.5,
+		MinRequests:      1,
+		Window:           time.Minute,
+		CoolDown:         10 * time.Second,
+		HalfOpenRequests: 1,
+	})
+	breaker.now = func() time.Time { return now }
+
+	var respond func() (*http.Response, error)
+	transport := breaker.Middleware()(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return respond()
+	}))
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+	host := req.URL.Host
+
+	respond = func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusBadGateway}, nil }
+	transport.RoundTrip(req)
+	if got := breaker.State(host); got != CircuitOpen {
+		t.Fatalf("state = %s, want open", got)
+	}
+
+	// A cancelled probe neither closes the circuit nor keeps its trial slot
+	now = now.Add(11 * time.Second)
+	respond = func() (*http.Response, error) { return nil, context.Canceled }
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if got := breaker.State(host); got != CircuitHalfOpen {
+		t.Fatalf("state after cancelled probe = %s, want half-open", got)
+	}
+
+	respond = func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if got := breaker.State(host); got != CircuitClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+}
@@ -0,0 +1,231 @@
+package godefaultapi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// EncodingGzip is the gzip content encoding
+	EncodingGzip = "gzip"
+	// EncodingDeflate is the deflate (zlib) content encoding
+	EncodingDeflate = "deflate"
+	// EncodingZstd is the Zstandard content encoding
+	EncodingZstd = "zstd"
+)
+
+// CompressionConfig holds request and response compression configuration
+type CompressionConfig struct {
+	// AcceptEncodings are the response encodings advertised in
+	// Accept-Encoding, in order of preference. Responses in these
+	// encodings are decompressed transparently.
+	AcceptEncodings []string
+	// RequestEncoding compresses request bodies with the given encoding.
+	// Empty sends bodies uncompressed.
+	RequestEncoding string
+	// MinRequestSize is the smallest request body, in bytes, that is compressed
+	MinRequestSize int
+}
+
+// DefaultCompressionConfig returns a default compression configuration that
+// accepts zstd, gzip and deflate responses and sends requests uncompressed
+func DefaultCompressionConfig() *CompressionConfig {
+	return &CompressionConfig{
+		AcceptEncodings: []string{EncodingZstd, EncodingGzip, EncodingDeflate},
+		MinRequestSize:  1024,
+	}
+}
+
+// SetCompression sets the compression configuration. A nil config restores
+// Go's default of transparent gzip responses and uncompressed requests.
+func (c *Client) SetCompression(config *CompressionConfig) error {
+	if config != nil {
+		if err := config.validate(); err != nil {
+			return err
+		}
+	}
+	c.compression = config
+	return nil
+}
+
+// validate checks that the configured encodings are supported
+func (config *CompressionConfig) validate() error {
+	for _, encoding := range append([]string{config.RequestEncoding}, config.AcceptEncodings...) {
+		switch encoding {
+		case "", EncodingGzip, EncodingDeflate, EncodingZstd:
+		default:
+			return fmt.Errorf("unsupported content encoding: %s", encoding)
+		}
+	}
+	return nil
+}
+
+// compressBody compresses a request body if configured and large enough,
+// returning the body to send and its content encoding
+func (c *Client) compressBody(body []byte) ([]byte, string, error) {
+	if c.compression == nil || c.compression.RequestEncoding == "" || len(body) == 0 || len(body) < c.compression.MinRequestSize {
+		return body, "", nil
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c.compression.RequestEncoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingDeflate:
+		w = zlib.NewWriter(&buf)
+	case EncodingZstd:
+		encoder, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, "", fmt.Errorf("error compressing request body: %w", err)
+		}
+		w = encoder
+	default:
+		return nil, "", fmt.Errorf("unsupported content encoding: %s", c.compression.RequestEncoding)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return nil, "", fmt.Errorf("error compressing request body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("error compressing request body: %w", err)
+	}
+	return buf.Bytes(), c.compression.RequestEncoding, nil
+}
+
+// decompressor returns a transport that advertises the accepted encodings
+// and decompresses responses
+func (c *Client) decompressor(next http.RoundTripper) http.RoundTripper {
+	acceptEncoding := strings.Join(c.compression.AcceptEncodings, ", ")
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if acceptEncoding == "" || req.Header.Get("Accept-Encoding") != "" {
+			return next.RoundTrip(req)
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || !hasResponseBody(req, resp) {
+			return resp, nil
+		}
+		body, err := decompressReader(encoding, resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if body == nil {
+			// Unknown encoding, leave the body as is
+			return resp, nil
+		}
+
+		resp.Body = body
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return resp, nil
+	})
+}
+
+// hasResponseBody reports whether resp carries a body to decode. Responses
+// to HEAD requests, 204 and 304 responses and empty bodies are left as they
+// are, since decoders fail on a missing header.
+func hasResponseBody(req *http.Request, resp *http.Response) bool {
+	if req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified || resp.ContentLength == 0 {
+		return false
+	}
+	body := bufio.NewReader(resp.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		return false
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}
+	return true
+}
+
+// decodeRequestBody returns the uncompressed form of a request body sent
+// with a Content-Encoding, or body itself if it cannot be decoded
+func decodeRequestBody(req *http.Request, body []byte) []byte {
+	encoding := strings.ToLower(req.Header.Get("Content-Encoding"))
+	if encoding == "" || len(body) == 0 {
+		return body
+	}
+	r, err := decompressReader(encoding, io.NopCloser(bytes.NewReader(body)))
+	if err != nil || r == nil {
+		return body
+	}
+	defer r.Close()
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// decompressReader wraps body in a decoder for encoding. It returns nil for
+// unknown encodings.
+func decompressReader(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing gzip response: %w", err)
+		}
+		return &decompressedBody{Reader: r, closers: []io.Closer{r, body}}, nil
+	case EncodingDeflate:
+		// Servers send either zlib-wrapped or raw deflate data
+		br := bufio.NewReader(body)
+		header, _ := br.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			r, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("error decompressing deflate response: %w", err)
+			}
+			return &decompressedBody{Reader: r, closers: []io.Closer{r, body}}, nil
+		}
+		r := flate.NewReader(br)
+		return &decompressedBody{Reader: r, closers: []io.Closer{r, body}}, nil
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing zstd response: %w", err)
+		}
+		r := decoder.IOReadCloser()
+		return &decompressedBody{Reader: r, closers: []io.Closer{r, body}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// decompressedBody closes the decoder and the underlying body
+type decompressedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer
+func (b *decompressedBody) Close() error {
+	var firstErr error
+	for _, closer := range b.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,224 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func decompress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	r, err := decompressReader(encoding, io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("decompressReader(%s) error = %v", encoding, err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading %s body: %v", encoding, err)
+	}
+	return out
+}
+
+func TestResponseDecompression(t *testing.T) {
+	payload := []byte(`{"id":1,"name":"` + strings.Repeat("a", 2048) + `"}`)
+	tests := []struct {
+		name     string
+		encoding string
+		header   string
+	}{
+		{"gzip", "gzip", "gzip"},
+		{"zlib deflate", "deflate", "deflate"},
+		{"raw deflate", "raw-deflate", "deflate"},
+		{"zstd", "zstd", "zstd"},
+		{"identity", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != "zstd, gzip, deflate" {
+					t.Errorf("Accept-Encoding = %q", got)
+				}
+				body := payload
+				if tt.encoding != "" {
+					body = compress(t, tt.encoding, payload)
+					w.Header().Set("Content-Encoding", tt.header)
+				}
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.SetResponseType(ContentTypeJSON)
+			if err := client.SetCompression(DefaultCompressionConfig()); err != nil {
+				t.Fatalf("SetCompression() error = %v", err)
+			}
+
+			var result struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			}
+			if err := client.Get(context.Background(), "/", nil, &result); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if result.ID != 1 || len(result.Name) != 2048 {
+				t.Errorf("result = {%d, %d chars}", result.ID, len(result.Name))
+			}
+		})
+	}
+}
+
+func TestResponseDecompressionBeforeMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compress(t, "gzip", []byte(`{"ok":true}`)))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetCompression(DefaultCompressionConfig())
+	var seen string
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err == nil {
+				seen = resp.Header.Get("Content-Encoding")
+			}
+			return resp, err
+		})
+	})
+
+	if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if seen != "" {
+		t.Errorf("middleware saw Content-Encoding %q, want decoded response", seen)
+	}
+}
+
+func TestResponseDecompressionWithoutBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		switch r.URL.Path {
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		case "/chunked":
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if err := client.SetCompression(DefaultCompressionConfig()); err != nil {
+		t.Fatalf("SetCompression() error = %v", err)
+	}
+	tests := []struct {
+		method, path string
+	}{
+		{http.MethodHead, "/"},
+		{http.MethodGet, "/no-content"},
+		{http.MethodGet, "/not-modified"},
+		{http.MethodGet, "/empty"},
+		{http.MethodGet, "/chunked"},
+	}
+	for _, tt := range tests {
+		if err := client.Do(context.Background(), tt.method, tt.path, nil, nil); err != nil {
+			t.Errorf("%s %s error = %v", tt.method, tt.path, err)
+		}
+	}
+}
+
+func TestRequestCompression(t *testing.T) {
+	large := []byte(`{"data":"` + strings.Repeat("x", 4096) + `"}`)
+	small := []byte(`{"data":"x"}`)
+	for _, encoding := range []string{EncodingGzip, EncodingDeflate, EncodingZstd} {
+		t.Run(encoding, func(t *testing.T) {
+			type received struct {
+				encoding string
+				body     []byte
+			}
+			var got []received
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if ce := r.Header.Get("Content-Encoding"); ce != "" {
+					body = decompress(t, ce, body)
+				}
+				got = append(got, received{r.Header.Get("Content-Encoding"), body})
+			}))
+			defer server.Close()
+
+			var signed []byte
+			client := NewClient(server.URL)
+			client.SetAuthenticator(AuthenticatorFunc(func(req *http.Request, body []byte) error {
+				signed = body
+				return nil
+			}))
+			config := DefaultCompressionConfig()
+			config.RequestEncoding = encoding
+			if err := client.SetCompression(config); err != nil {
+				t.Fatalf("SetCompression() error = %v", err)
+			}
+
+			if err := client.Post(context.Background(), "/", large, nil); err != nil {
+				t.Fatalf("Post(large) error = %v", err)
+			}
+			if len(signed) >= len(large) {
+				t.Errorf("authenticator signed %d bytes, want the compressed body", len(signed))
+			}
+			if err := client.Post(context.Background(), "/", small, nil); err != nil {
+				t.Fatalf("Post(small) error = %v", err)
+			}
+
+			if len(got) != 2 {
+				t.Fatalf("server received %d requests, want 2", len(got))
+			}
+			if got[0].encoding != encoding || !bytes.Equal(got[0].body, large) {
+				t.Errorf("large body: encoding %q, %d bytes", got[0].encoding, len(got[0].body))
+			}
+			if got[1].encoding != "" || !bytes.Equal(got[1].body, small) {
+				t.Errorf("small body: encoding %q, body %q", got[1].encoding, got[1].body)
+			}
+		})
+	}
+}
+
+func TestSetCompressionRejectsUnknownEncoding(t *testing.T) {
+	client := NewClient("http://example.invalid")
+	if err := client.SetCompression(&CompressionConfig{RequestEncoding: "br"}); err == nil {
+		t.Error("SetCompression(br) error = nil")
+	}
+	if err := client.SetCompression(&CompressionConfig{AcceptEncodings: []string{"compress"}}); err == nil {
+		t.Error("SetCompression(compress) error = nil")
+	}
+}
@@ -0,0 +1,616 @@
+package godefaultapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Config describes a client. It is usually loaded from a file with
+// LoadConfig and turned into a client with NewClient. Keys are the snake
+// case field names, e.g. rate_limit.max_retries.
+type Config struct {
+	// BaseURL is the API base URL, or a Qualys platform name such as "US3"
+	BaseURL string `json:"base_url"`
+	// FailoverURLs are base URLs or platform names tried after BaseURL
+	FailoverURLs []string `json:"failover_urls"`
+	// FailoverCooldown is how long a failed base URL is avoided
+	FailoverCooldown time.Duration `json:"failover_cooldown"`
+	// Auth configures authentication
+	Auth AuthConfig `json:"auth"`
+	// RequestType is the request content type, "json" or "xml"
+	RequestType string `json:"request_type"`
+	// ResponseType is the response content type, "json" or "xml"
+	ResponseType string `json:"response_type"`
+	// Headers are sent with every request
+	Headers map[string]string `json:"headers"`
+	// Timeout is the timeout for each request attempt
+	Timeout time.Duration `json:"timeout"`
+	// RateLimit configures rate limit retries
+	RateLimit *RateLimitConfig `json:"rate_limit"`
+	// Compression configures request and response compression
+	Compression *CompressionConfig `json:"compression"`
+	// TLS configures TLS
+	TLS TLSConfig `json:"tls"`
+	// Transport tunes connection reuse and HTTP/2
+	Transport *TransportConfig `json:"transport"`
+}
+
+// AuthConfig configures authentication
+type AuthConfig struct {
+	// Method is "basic", "bearer", "api_key", "session" (Qualys session
+	// login) or empty for none
+	Method string `json:"method"`
+	// Username is the basic or session auth username
+	Username string `json:"username"`
+	// Password is the basic or session auth password
+	Password string `json:"password"`
+	// Token is the bearer token
+	Token string `json:"token"`
+	// APIKey is the API key
+	APIKey string `json:"api_key"`
+	// APIKeyHeader is the header the API key is sent in
+	APIKeyHeader string `json:"api_key_header"`
+	// APIKeyQueryParam is the query parameter the API key is sent in, used
+	// when APIKeyHeader is empty
+	APIKeyQueryParam string `json:"api_key_query_param"`
+}
+
+// TLSConfig configures TLS
+type TLSConfig struct {
+	// CAFile is a PEM file of additional trusted certificate authorities
+	CAFile string `json:"ca_file"`
+	// CertFile and KeyFile are a PEM client certificate and key
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ServerName overrides the server name used to verify certificates
+	ServerName string `json:"server_name"`
+	// MinVersion is the minimum TLS version, "1.2" or "1.3"
+	MinVersion string `json:"min_version"`
+	// InsecureSkipVerify disables certificate verification, for testing only
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// ConfigError reports an invalid configuration value
+type ConfigError struct {
+	// Source is the file or environment variable the value came from
+	Source string
+	// Key is the dotted key of the value, e.g. "rate_limit.max_retries"
+	Key string
+	// Err describes the problem
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.Source != "" {
+		b.WriteString(e.Source + ": ")
+	}
+	if e.Key != "" {
+		b.WriteString(e.Key + ": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// LoadConfig reads a JSON or YAML configuration file, chosen by its
+// extension, and applies environment overrides. With envPrefix "QUALYS",
+// QUALYS_AUTH_PASSWORD overrides auth.password and QUALYS_FAILOVER_URLS
+// takes a comma separated list. Headers cannot be set from the environment.
+// An empty envPrefix disables overrides. Errors are *ConfigError values
+// naming the offending key.
+func LoadConfig(path, envPrefix string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		tree, err = parseSimpleYAML(data)
+	default:
+		err = json.Unmarshal(data, &tree)
+	}
+	if err != nil {
+		return nil, &ConfigError{Source: path, Err: err}
+	}
+	if tree == nil {
+		tree = make(map[string]interface{})
+	}
+
+	sources := make(map[string]string)
+	if envPrefix != "" {
+		applyEnvOverrides(tree, reflect.TypeOf(Config{}), "", strings.ToUpper(envPrefix), sources)
+	}
+
+	config := DefaultConfig()
+	if err := decodeConfigValue("", tree, reflect.ValueOf(config).Elem()); err != nil {
+		return nil, withConfigSource(err, path, sources)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, withConfigSource(err, path, sources)
+	}
+	return config, nil
+}
+
+// DefaultConfig returns the configuration of a client created with NewClient
+func DefaultConfig() *Config {
+	return &Config{
+		RequestType:      "json",
+		ResponseType:     "xml",
+		Timeout:          30 * time.Second,
+		FailoverCooldown: defaultFailoverCooldown,
+		RateLimit:        DefaultRateLimitConfig(),
+	}
+}
+
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	invalid := func(key, format string, args ...interface{}) error {
+		return &ConfigError{Key: key, Err: fmt.Errorf(format, args...)}
+	}
+
+	if c.BaseURL == "" {
+		return invalid("base_url", "is required")
+	}
+	for i, nameOrURL := range append([]string{c.BaseURL}, c.FailoverURLs...) {
+		if _, err := ResolveBaseURL(nameOrURL); err != nil {
+			key := "base_url"
+			if i > 0 {
+				key = fmt.Sprintf("failover_urls[%d]", i-1)
+			}
+			return invalid(key, "%w", err)
+		}
+	}
+	if c.FailoverCooldown < 0 {
+		return invalid("failover_cooldown", "must not be negative")
+	}
+	if _, err := parseContentType(c.RequestType); err != nil {
+		return invalid("request_type", "%w", err)
+	}
+	if _, err := parseContentType(c.ResponseType); err != nil {
+		return invalid("response_type", "%w", err)
+	}
+	if c.Timeout < 0 {
+		return invalid("timeout", "must not be negative")
+	}
+	if c.RateLimit != nil {
+		if c.RateLimit.MaxRetries < 0 {
+			return invalid("rate_limit.max_retries", "must not be negative")
+		}
+		if c.RateLimit.HeaderName == "" {
+			return invalid("rate_limit.header_name", "is required")
+		}
+	}
+	if c.Compression != nil {
+		if err := c.Compression.validate(); err != nil {
+			return invalid("compression", "%w", err)
+		}
+	}
+
+	switch c.Auth.Method {
+	case "":
+	case "basic", "session":
+		if c.Auth.Username == "" {
+			return invalid("auth.username", "is required for %s auth", c.Auth.Method)
+		}
+		if c.Auth.Password == "" {
+			return invalid("auth.password", "is required for %s auth", c.Auth.Method)
+		}
+	case "bearer":
+		if c.Auth.Token == "" {
+			return invalid("auth.token", "is required for bearer auth")
+		}
+	case "api_key":
+		if c.Auth.APIKey == "" {
+			return invalid("auth.api_key", "is required for api_key auth")
+		}
+		if c.Auth.APIKeyHeader == "" && c.Auth.APIKeyQueryParam == "" {
+			return invalid("auth.api_key_header", "api_key auth requires a header or query parameter name")
+		}
+	default:
+		return invalid("auth.method", "unknown method %q, expected basic, bearer, api_key or session", c.Auth.Method)
+	}
+
+	if c.Transport != nil {
+		limits := []struct {
+			key   string
+			value int
+		}{
+			{"transport.max_idle_conns", c.Transport.MaxIdleConns},
+			{"transport.max_idle_conns_per_host", c.Transport.MaxIdleConnsPerHost},
+			{"transport.max_conns_per_host", c.Transport.MaxConnsPerHost},
+		}
+		for _, limit := range limits {
+			if limit.value < 0 {
+				return invalid(limit.key, "must not be negative")
+			}
+		}
+		if c.Transport.IdleConnTimeout < 0 {
+			return invalid("transport.idle_conn_timeout", "must not be negative")
+		}
+	}
+
+	switch c.TLS.MinVersion {
+	case "", "1.2", "1.3":
+	default:
+		return invalid("tls.min_version", "unsupported version %q, expected 1.2 or 1.3", c.TLS.MinVersion)
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return invalid("tls.cert_file", "cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// NewClient creates a client from the configuration
+func (c *Config) NewClient() (*Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := NewClient("")
+	names := append([]string{c.BaseURL}, c.FailoverURLs...)
+	if err := client.SetPlatforms(names...); err != nil {
+		return nil, err
+	}
+	client.SetFailoverCooldown(c.FailoverCooldown)
+
+	requestType, _ := parseContentType(c.RequestType)
+	responseType, _ := parseContentType(c.ResponseType)
+	client.SetRequestType(requestType)
+	client.SetResponseType(responseType)
+	for key, value := range c.Headers {
+		client.SetHeader(key, value)
+	}
+	client.httpClient.Timeout = c.Timeout
+	if c.RateLimit != nil {
+		rateLimit := *c.RateLimit
+		client.SetRateLimitConfig(&rateLimit)
+	}
+	if c.Compression != nil {
+		compression := *c.Compression
+		client.SetCompression(&compression)
+	}
+
+	tlsConfig, err := c.TLS.load()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.httpClient.Transport = transport
+	}
+	if c.Transport != nil {
+		if err := client.SetTransportConfig(c.Transport); err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.Auth.Method {
+	case "basic":
+		client.SetBasicAuth(c.Auth.Username, c.Auth.Password)
+	case "bearer":
+		client.SetBearerToken(c.Auth.Token)
+	case "api_key":
+		client.SetAuthenticator(&APIKeyAuth{Key: c.Auth.APIKey, Header: c.Auth.APIKeyHeader, QueryParam: c.Auth.APIKeyQueryParam})
+	case "session":
+		NewSessionAuthenticator(client, QualysSessionConfig(c.Auth.Username, c.Auth.Password))
+	}
+	return client, nil
+}
+
+// load builds the TLS configuration, or returns nil if the defaults apply
+func (t TLSConfig) load() (*tls.Config, error) {
+	if t == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	switch t.MinVersion {
+	case "1.2":
+		config.MinVersion = tls.VersionTLS12
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, &ConfigError{Key: "tls.ca_file", Err: err}
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, &ConfigError{Key: "tls.ca_file", Err: fmt.Errorf("no certificates found in %s", t.CAFile)}
+		}
+		config.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, &ConfigError{Key: "tls.cert_file", Err: err}
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// parseContentType parses a content type name such as "json" or a MIME type
+func parseContentType(name string) (ContentType, error) {
+	switch strings.ToLower(name) {
+	case "json", string(ContentTypeJSON):
+		return ContentTypeJSON, nil
+	case "xml", string(ContentTypeXML):
+		return ContentTypeXML, nil
+	default:
+		return "", fmt.Errorf("unsupported content type %q, expected json or xml", name)
+	}
+}
+
+// withConfigSource sets the source of a ConfigError to the environment
+// variable its key came from, or to the file
+func withConfigSource(err error, path string, sources map[string]string) error {
+	if configErr, ok := err.(*ConfigError); ok && configErr.Source == "" {
+		configErr.Source = path
+		if env, ok := sources[configErr.Key]; ok {
+			configErr.Source = env
+		}
+	}
+	return err
+}
+
+// applyEnvOverrides sets the values of prefixed environment variables in
+// tree for every key of t, recording which variable each came from
+func applyEnvOverrides(tree map[string]interface{}, t reflect.Type, keyPrefix, envPrefix string, sources map[string]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for key, field := range configFields(t) {
+		fullKey := joinConfigKey(keyPrefix, key)
+		env := envPrefix + "_" + strings.ToUpper(key)
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		switch {
+		case fieldType.Kind() == reflect.Struct:
+			child, ok := tree[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+			}
+			applyEnvOverrides(child, fieldType, fullKey, env, sources)
+			if len(child) > 0 {
+				tree[key] = child
+			}
+		case fieldType.Kind() == reflect.Map:
+			// Map keys such as header names cannot be recovered from variable names
+		default:
+			if value, ok := os.LookupEnv(env); ok {
+				tree[key] = value
+				sources[fullKey] = env
+			}
+		}
+	}
+}
+
+// durationType is the reflect type of time.Duration
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// configDefaults return the defaults for configuration sections that are
+// only created when present
+var configDefaults = map[reflect.Type]func() interface{}{
+	reflect.TypeOf(RateLimitConfig{}):   func() interface{} { return DefaultRateLimitConfig() },
+	reflect.TypeOf(CompressionConfig{}): func() interface{} { return DefaultCompressionConfig() },
+	reflect.TypeOf(TransportConfig{}):   func() interface{} { return DefaultTransportConfig() },
+}
+
+// decodeConfigValue decodes a parsed JSON or YAML value into dst. YAML
+// scalars are strings, so strings are accepted for numbers and booleans.
+func decodeConfigValue(key string, src interface{}, dst reflect.Value) error {
+	if src == nil {
+		return nil
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return &ConfigError{Key: key, Err: fmt.Errorf(format, args...)}
+	}
+
+	if dst.Type() == durationType {
+		switch v := src.(type) {
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return invalid("invalid duration %q, expected a value such as 30s or 5m", v)
+			}
+			dst.SetInt(int64(d))
+		case float64:
+			dst.SetInt(int64(v * float64(time.Second)))
+		default:
+			return invalid("must be a duration")
+		}
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			if newDefault, ok := configDefaults[dst.Type().Elem()]; ok {
+				dst.Set(reflect.ValueOf(newDefault()))
+			} else {
+				dst.Set(reflect.New(dst.Type().Elem()))
+			}
+		}
+		return decodeConfigValue(key, src, dst.Elem())
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return invalid("must be a mapping")
+		}
+		fields := configFields(dst.Type())
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			field, ok := fields[k]
+			if !ok {
+				return &ConfigError{Key: joinConfigKey(key, k), Err: fmt.Errorf("unknown key")}
+			}
+			if err := decodeConfigValue(joinConfigKey(key, k), m[k], dst.FieldByIndex(field.Index)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return invalid("must be a mapping")
+		}
+		values := reflect.MakeMapWithSize(dst.Type(), len(m))
+		for k, v := range m {
+			s, ok := configScalar(v)
+			if !ok {
+				return &ConfigError{Key: joinConfigKey(key, k), Err: fmt.Errorf("must be a string")}
+			}
+			values.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(s))
+		}
+		dst.Set(values)
+	case reflect.Slice:
+		var items []interface{}
+		switch v := src.(type) {
+		case []interface{}:
+			items = v
+		case string:
+			// Comma separated, as set from the environment
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		default:
+			return invalid("must be a list")
+		}
+		values := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeConfigValue(fmt.Sprintf("%s[%d]", key, i), item, values.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(values)
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		dst.SetString(s)
+	case reflect.Int:
+		switch v := src.(type) {
+		case float64:
+			if v != float64(int(v)) {
+				return invalid("must be a whole number")
+			}
+			dst.SetInt(int64(v))
+		case string:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return invalid("invalid number %q", v)
+			}
+			dst.SetInt(int64(n))
+		default:
+			return invalid("must be a number")
+		}
+	case reflect.Bool:
+		switch v := src.(type) {
+		case bool:
+			dst.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return invalid("invalid boolean %q", v)
+			}
+			dst.SetBool(b)
+		default:
+			return invalid("must be true or false")
+		}
+	default:
+		return invalid("unsupported setting type %s", dst.Type())
+	}
+	return nil
+}
+
+// configScalar formats a scalar value as a string
+func configScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// configFields returns the settable fields of a struct by key, the json tag
+// name or the snake case field name
+func configFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Type.Kind() == reflect.Func {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = snakeCase(field.Name)
+		}
+		fields[key] = field
+	}
+	return fields
+}
+
+// snakeCase converts a Go field name such as MaxRetries to max_retries
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// joinConfigKey joins a parent and child key with a dot
+func joinConfigKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
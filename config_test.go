@@ -0,0 +1,190 @@
+package godefaultapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a configuration file to a temporary directory
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	path := writeConfig(t, "client.yaml", `
+base_url: US3
+failover_urls: [EU1]
+failover_cooldown: 1m
+auth:
+  method: basic
+  username: user
+  password: secret
+response_type: json
+headers:
+  X-Requested-With: godefaultapi
+timeout: 10s
+rate_limit:
+  max_retries: 5
+tls:
+  min_version: "1.2"
+transport:
+  http2: false
+`)
+	config, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if config.BaseURL != "US3" || len(config.FailoverURLs) != 1 || config.FailoverURLs[0] != "EU1" {
+		t.Errorf("base URLs = %q, %q", config.BaseURL, config.FailoverURLs)
+	}
+	if config.FailoverCooldown != time.Minute || config.Timeout != 10*time.Second {
+		t.Errorf("durations = %v, %v", config.FailoverCooldown, config.Timeout)
+	}
+	if config.RateLimit.MaxRetries != 5 || config.RateLimit.HeaderName != DefaultRateLimitConfig().HeaderName {
+		t.Errorf("rate limit = %+v, want defaults with 5 retries", config.RateLimit)
+	}
+	if config.RequestType != "json" {
+		t.Errorf("request type = %q, want default json", config.RequestType)
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	urls := client.BaseURLs()
+	if len(urls) != 2 || urls[0] != "https://qualysapi.qg3.apps.qualys.com" || urls[1] != "https://qualysapi.qualys.eu" {
+		t.Errorf("BaseURLs() = %v", urls)
+	}
+	if client.responseType != ContentTypeJSON || client.headers["X-Requested-With"] != "godefaultapi" {
+		t.Errorf("client = %v, %v", client.responseType, client.headers)
+	}
+	if !strings.HasPrefix(client.headers["Authorization"], "Basic ") {
+		t.Errorf("Authorization = %q, want basic auth", client.headers["Authorization"])
+	}
+	if client.httpClient.Timeout != 10*time.Second || client.httpClient.Transport == nil {
+		t.Errorf("http client = %+v, want timeout and TLS transport", client.httpClient)
+	}
+	transport := client.httpClient.Transport.(*http.Transport)
+	if transport.Protocols.HTTP2() || transport.MaxIdleConnsPerHost != DefaultTransportConfig().MaxIdleConnsPerHost {
+		t.Errorf("transport protocols = %v, idle per host = %d, want defaults without HTTP/2", transport.Protocols, transport.MaxIdleConnsPerHost)
+	}
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("TLS min version = %x, want TLS 1.2 kept", transport.TLSClientConfig.MinVersion)
+	}
+}
+
+func TestLoadConfigJSONWithEnvOverrides(t *testing.T) {
+	var gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-API-Key")
+		w.Write([]byte("<OK/>"))
+	}))
+	defer server.Close()
+
+	path := writeConfig(t, "client.json", `{
+		"base_url": "https://unused.invalid",
+		"auth": {"method": "api_key", "api_key_header": "X-API-Key"},
+		"rate_limit": {"max_retries": 2}
+	}`)
+	t.Setenv("TESTAPI_BASE_URL", server.URL)
+	t.Setenv("TESTAPI_AUTH_API_KEY", "from-env")
+	t.Setenv("TESTAPI_RATE_LIMIT_MAX_RETRIES", "7")
+
+	config, err := LoadConfig(path, "testapi")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if config.RateLimit.MaxRetries != 7 {
+		t.Errorf("max retries = %d, want 7", config.RateLimit.MaxRetries)
+	}
+	client, err := config.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if gotKey != "from-env" {
+		t.Errorf("X-API-Key = %q, want from-env", gotKey)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		wantKey string
+		wantSrc string
+	}{
+		{"unknown key", "c.yaml", "base_url: US1\nrate_limit:\n  max_retry: 3\n", nil, "rate_limit.max_retry", "c.yaml"},
+		{"bad number", "c.json", `{"base_url": "US1", "rate_limit": {"max_retries": "many"}}`, nil, "rate_limit.max_retries", "c.json"},
+		{"bad duration", "c.yaml", "base_url: US1\ntimeout: soon\n", nil, "timeout", "c.yaml"},
+		{"missing base URL", "c.yaml", "timeout: 5s\n", nil, "base_url", "c.yaml"},
+		{"unknown platform", "c.yaml", "base_url: US1\nfailover_urls:\n  - XX9\n", nil, "failover_urls[0]", "c.yaml"},
+		{"bad content type", "c.yaml", "base_url: US1\nresponse_type: csv\n", nil, "response_type", "c.yaml"},
+		{"missing password", "c.yaml", "base_url: US1\nauth:\n  method: basic\n  username: u\n", nil, "auth.password", "c.yaml"},
+		{"unknown auth method", "c.yaml", "base_url: US1\nauth:\n  method: oauth\n", nil, "auth.method", "c.yaml"},
+		{"negative transport limit", "c.yaml", "base_url: US1\ntransport:\n  max_conns_per_host: -1\n", nil, "transport.max_conns_per_host", "c.yaml"},
+		{"bad env value", "c.yaml", "base_url: US1\n", map[string]string{"TESTAPI_TIMEOUT": "x"}, "timeout", "TESTAPI_TIMEOUT"},
+		{"bad env platform", "c.yaml", "base_url: US1\n", map[string]string{"TESTAPI_BASE_URL": "XX9"}, "base_url", "TESTAPI_BASE_URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := LoadConfig(writeConfig(t, tt.file, tt.content), "TESTAPI")
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("LoadConfig() error = %v, want *ConfigError", err)
+			}
+			if configErr.Key != tt.wantKey || !strings.HasSuffix(configErr.Source, tt.wantSrc) {
+				t.Errorf("error = %q, want key %q from %q", err, tt.wantKey, tt.wantSrc)
+			}
+		})
+	}
+}
+
+func TestConfigTLS(t *testing.T) {
+	config := DefaultConfig()
+	config.BaseURL = "https://example.com"
+	config.TLS.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	_, err := config.NewClient()
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Key != "tls.ca_file" {
+		t.Errorf("NewClient() error = %v, want tls.ca_file error", err)
+	}
+
+	config.TLS = TLSConfig{CertFile: "client.pem"}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "tls.cert_file") {
+		t.Errorf("Validate() error = %v, want tls.cert_file error", err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"MaxRetries":      "max_retries",
+		"APIKeyHeader":    "api_key_header",
+		"AcceptEncodings": "accept_encodings",
+		"BaseURL":         "base_url",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package godefaultapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SetCookieJar sets the cookie jar used to store and send cookies. A nil jar
+// disables cookies.
+func (c *Client) SetCookieJar(jar http.CookieJar) {
+	c.httpClient.Jar = jar
+}
+
+// FileCookieJar is a cookie jar that can be saved to and loaded from a file,
+// so that sessions survive between runs
+type FileCookieJar struct {
+	path string
+
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	cookies map[string]savedCookie
+	now     func() time.Time
+}
+
+// savedCookie is a cookie as stored on disk, with the URL it was set for
+type savedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// NewFileCookieJar creates a cookie jar persisted at path, loading any
+// cookies saved there that have not expired. A missing file is not an error.
+func NewFileCookieJar(path string) (*FileCookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cookie jar: %w", err)
+	}
+	j := &FileCookieJar{
+		path:    path,
+		jar:     jar,
+		cookies: make(map[string]savedCookie),
+		now:     time.Now,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cookie jar: %w", err)
+	}
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("error decoding cookie jar %s: %w", path, err)
+	}
+	for _, entry := range saved {
+		u, err := url.Parse(entry.URL)
+		if err != nil || entry.Cookie == nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{entry.Cookie})
+	}
+	return j, nil
+}
+
+// SetCookies implements http.CookieJar
+func (j *FileCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+
+	now := j.now()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	for _, cookie := range cookies {
+		key := u.Host + "|" + cookie.Domain + "|" + cookie.Path + "|" + cookie.Name
+
+		// Store an absolute expiry so that MaxAge is not restarted on load
+		saved := *cookie
+		if saved.MaxAge > 0 {
+			saved.Expires = now.Add(time.Duration(saved.MaxAge) * time.Second)
+			saved.MaxAge = 0
+		}
+		if cookie.MaxAge < 0 || (!saved.Expires.IsZero() && !saved.Expires.After(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = savedCookie{URL: origin, Cookie: &saved}
+	}
+}
+
+// Cookies implements http.CookieJar
+func (j *FileCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// Save writes the unexpired cookies to the jar's file. The file is readable
+// only by its owner since it holds session credentials.
+func (j *FileCookieJar) Save() error {
+	j.mu.Lock()
+	now := j.now()
+	keys := make([]string, 0, len(j.cookies))
+	for key := range j.cookies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	saved := make([]savedCookie, 0, len(keys))
+	for _, key := range keys {
+		entry := j.cookies[key]
+		if !entry.Cookie.Expires.IsZero() && !entry.Cookie.Expires.After(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		saved = append(saved, entry)
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cookie jar: %w", err)
+	}
+	if err := writeFileAtomic(j.path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing cookie jar: %w", err)
+	}
+	return nil
+}
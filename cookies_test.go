@@ -0,0 +1,64 @@
+package godefaultapi
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileCookieJarPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	u, _ := url.Parse("https://qualysapi.qualys.com/api/2.0/fo/session/")
+
+	jar, err := NewFileCookieJar(path)
+	if err != nil {
+		t.Fatalf("NewFileCookieJar() error = %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "QualysSession", Value: "abc", Path: "/api"},
+		{Name: "short", Value: "1", MaxAge: 3600},
+		{Name: "expired", Value: "1", Expires: time.Now().Add(-time.Hour)},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "deleted", Value: "1"}})
+	jar.SetCookies(u, []*http.Cookie{{Name: "deleted", MaxAge: -1}})
+	if err := jar.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("cookie file mode = %v, want 0600", perm)
+	}
+
+	loaded, err := NewFileCookieJar(path)
+	if err != nil {
+		t.Fatalf("NewFileCookieJar(saved) error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, cookie := range loaded.Cookies(u) {
+		got[cookie.Name] = cookie.Value
+	}
+	want := map[string]string{"QualysSession": "abc", "short": "1"}
+	if len(got) != len(want) || got["QualysSession"] != "abc" || got["short"] != "1" {
+		t.Errorf("loaded cookies = %v, want %v", got, want)
+	}
+}
+
+func TestFileCookieJarMissingAndInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewFileCookieJar(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("NewFileCookieJar(missing) error = %v", err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte("{"), 0o600)
+	if _, err := NewFileCookieJar(invalid); err == nil {
+		t.Error("NewFileCookieJar(invalid) error = nil")
+	}
+}
@@ -0,0 +1,322 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrIncompleteDownload is returned when a download ends before all of the
+// content was received
+var ErrIncompleteDownload = errors.New("download incomplete")
+
+// ErrDownloadChanged is returned when the content changed while a download
+// to a writer that cannot be truncated was being resumed
+var ErrDownloadChanged = errors.New("download content changed")
+
+// maxDownloadResumes is how many times an interrupted download is resumed
+// before giving up
+const maxDownloadResumes = 3
+
+// headersKey is the context key for per-call request headers
+type headersKey struct{}
+
+// withHeaders returns a context whose requests carry header, overriding the
+// client's headers and any per-call headers already in ctx
+func withHeaders(ctx context.Context, header http.Header) context.Context {
+	merged := headersFromContext(ctx).Clone()
+	if merged == nil {
+		merged = make(http.Header)
+	}
+	for key, values := range header {
+		merged[key] = values
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// headersFromContext returns the per-call request headers, if any
+func headersFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(headersKey{}).(http.Header)
+	return header
+}
+
+// downloadKey is the context key marking download requests
+type downloadKey struct{}
+
+// isDownload reports whether ctx belongs to a download
+func isDownload(ctx context.Context) bool {
+	return ctx.Value(downloadKey{}) != nil
+}
+
+// Download streams the response to a GET of path to dst without buffering it
+// in memory, and returns the number of bytes written. If the transfer is
+// interrupted it is resumed with a Range request. The client timeout does not
+// apply to downloads, use ctx to bound them. progress may be nil.
+func (c *Client) Download(ctx context.Context, path string, dst io.Writer, progress ProgressReporter) (int64, error) {
+	var validator string
+	return c.download(ctx, path, dst, 0, &validator, progress)
+}
+
+// DownloadFile downloads path to filename and returns its size. Data is
+// written to filename.part and renamed into place once complete, so filename
+// never holds a partial download. A .part file left by an earlier interrupted
+// download is resumed rather than fetched again, if the ETag or Last-Modified
+// date saved with it in filename.part.etag still matches the content.
+// progress may be nil.
+func (c *Client) DownloadFile(ctx context.Context, path, filename string, progress ProgressReporter) (int64, error) {
+	partName := filename + ".part"
+	validatorName := partName + ".etag"
+	f, err := os.OpenFile(partName, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("error creating download file: %w", err)
+	}
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("error creating download file: %w", err)
+	}
+
+	// Without a validator the partial content cannot be checked, so restart
+	var validator string
+	if saved, err := os.ReadFile(validatorName); err == nil {
+		validator = strings.TrimSpace(string(saved))
+	}
+	if offset > 0 && validator == "" {
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return 0, fmt.Errorf("error restarting download: %w", err)
+		}
+		if offset, err = f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return 0, fmt.Errorf("error restarting download: %w", err)
+		}
+	}
+
+	size, err := c.download(ctx, path, f, offset, &validator, progress)
+	if err != nil {
+		// Keep any partial content and its validator so the next call can
+		// resume it
+		f.Close()
+		if size == 0 || validator == "" {
+			os.Remove(validatorName)
+		} else {
+			os.WriteFile(validatorName, []byte(validator+"\n"), 0o644)
+		}
+		if size == 0 {
+			os.Remove(partName)
+		}
+		return size, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return size, fmt.Errorf("error writing download file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return size, fmt.Errorf("error writing download file: %w", err)
+	}
+	if err := os.Rename(partName, filename); err != nil {
+		return size, fmt.Errorf("error writing download file: %w", err)
+	}
+	os.Remove(validatorName)
+	return size, nil
+}
+
+// download copies path to w starting at offset, the number of bytes w
+// already holds, resuming interrupted transfers. validator identifies the
+// version of the content w holds and is updated as the download proceeds. It
+// returns the number of bytes w holds when done.
+func (c *Client) download(ctx context.Context, path string, w io.Writer, offset int64, validator *string, progress ProgressReporter) (int64, error) {
+	ctx, path, err := resolvePath(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	if progress == nil {
+		progress = NopProgressReporter{}
+	}
+	ctx = context.WithValue(ctx, downloadKey{}, true)
+	progress.Start(-1)
+	defer progress.Finish()
+	if offset > 0 {
+		progress.Advance(offset)
+	}
+
+	for resumes := 0; ; resumes++ {
+		var err error
+		offset, err = c.downloadPart(ctx, path, w, offset, validator, progress)
+		if err == nil || !errors.Is(err, ErrIncompleteDownload) || ctx.Err() != nil || resumes >= maxDownloadResumes {
+			return offset, err
+		}
+	}
+}
+
+// downloadPart makes a single request for path from offset and copies the
+// content to w. It returns the number of bytes w holds afterwards.
+// Interrupted transfers return an error wrapping ErrIncompleteDownload.
+// validator is sent in If-Range so that a range of changed content is never
+// appended to w, and updated from the response.
+func (c *Client) downloadPart(ctx context.Context, path string, w io.Writer, offset int64, validator *string, progress ProgressReporter) (int64, error) {
+	// Ask for the raw bytes so that Content-Length and ranges match what is written
+	header := make(http.Header)
+	header.Set("Accept", "*/*")
+	header.Set("Accept-Encoding", "identity")
+	header.Set("Cache-Control", "no-store")
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if *validator != "" {
+			header.Set("If-Range", *validator)
+		}
+	}
+
+	var n int64
+	_, err := c.call(withHeaders(ctx, header), http.MethodGet, path, nil, func(resp *http.Response) ([]byte, error) {
+		size := int64(-1)
+		switch {
+		case resp.StatusCode == http.StatusPartialContent:
+			start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+			if !ok || start != offset {
+				return nil, fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+			}
+			size = total
+		case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+			// The range starts at the end of the content, so it is already complete
+			if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total == offset {
+				progress.SetTotal(total)
+				return nil, nil
+			}
+			return responseError(resp)
+		case resp.StatusCode >= 400:
+			return responseError(resp)
+		default:
+			if resp.ContentLength >= 0 {
+				size = resp.ContentLength
+			}
+			if offset > 0 {
+				// The content changed or the server ignored the Range header
+				restarted, err := restartDownload(w, resp, offset, *validator)
+				if err != nil {
+					return nil, err
+				}
+				if restarted {
+					progress.Advance(-offset)
+					offset = 0
+				}
+			}
+			*validator = ""
+		}
+		if *validator == "" {
+			*validator = responseValidator(resp)
+		}
+		progress.SetTotal(size)
+
+		body := &downloadReader{r: resp.Body, progress: progress}
+		var err error
+		n, err = io.Copy(w, body)
+		if body.err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrIncompleteDownload, body.err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing download: %w", err)
+		}
+		if size >= 0 && offset+n != size {
+			return nil, fmt.Errorf("%w: received %d of %d bytes", ErrIncompleteDownload, offset+n, size)
+		}
+		return nil, nil
+	})
+	return offset + n, err
+}
+
+// truncater is a writer that can be emptied to restart a download, such as
+// an *os.File
+type truncater interface {
+	Truncate(size int64) error
+	Seek(offset int64, whence int) (int64, error)
+}
+
+// restartDownload handles a full response to a resumed download. If w can
+// be truncated it is emptied and restarted reports true. Otherwise, unless
+// the content changed, the part w already holds is skipped.
+func restartDownload(w io.Writer, resp *http.Response, offset int64, validator string) (restarted bool, err error) {
+	if t, ok := w.(truncater); ok {
+		if err := t.Truncate(0); err != nil {
+			return false, fmt.Errorf("error restarting download: %w", err)
+		}
+		if _, err := t.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("error restarting download: %w", err)
+		}
+		return true, nil
+	}
+	if validator != "" && responseValidator(resp) != validator {
+		return false, ErrDownloadChanged
+	}
+	if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrIncompleteDownload, err)
+	}
+	return false, nil
+}
+
+// responseValidator returns the strong ETag or Last-Modified date of a
+// response, for use in If-Range
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// downloadReader reports progress and records read errors, so that they can
+// be told apart from write errors
+type downloadReader struct {
+	r        io.Reader
+	progress ProgressReporter
+	err      error
+}
+
+func (r *downloadReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.progress.Advance(int64(n))
+	}
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// parseContentRange parses a Content-Range header such as
+// "bytes 100-199/1000" or "bytes */1000", returning the first byte position
+// and the total size, or -1 if the size is unknown
+func parseContentRange(value string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	total = -1
+	if size != "*" {
+		var err error
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rng == "*" {
+		return 0, total, true
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
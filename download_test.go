@@ -0,0 +1,336 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingProgress records progress updates
+type countingProgress struct {
+	mu        sync.Mutex
+	total     int64
+	completed int64
+	finished  bool
+}
+
+func (p *countingProgress) Start(total int64) { p.total = total }
+func (p *countingProgress) SetTotal(total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+}
+func (p *countingProgress) Advance(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed += n
+}
+func (p *countingProgress) Finish() { p.finished = true }
+
+// downloadServer serves content with Range support and ETag "v1". The first
+// interrupt requests are cut off halfway through.
+func downloadServer(t *testing.T, content []byte, interrupt int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var ranges []string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if n <= interrupt {
+			start := 0
+			if rng := r.Header.Get("Range"); rng != "" {
+				start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+				w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(content)-1)+"/"+strconv.Itoa(len(content)))
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)-start))
+				w.WriteHeader(http.StatusPartialContent)
+			} else {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			}
+			// Send half of what was promised and drop the connection
+			w.Write(content[start : start+(len(content)-start)/2])
+			return
+		}
+		http.ServeContent(w, r, "report.csv", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &ranges
+}
+
+func TestDownloadResumesInterruptedTransfer(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	server, ranges := downloadServer(t, content, 2)
+
+	client := NewClient(server.URL)
+	progress := &countingProgress{}
+	var buf bytes.Buffer
+	n, err := client.Download(context.Background(), "/report", &buf, progress)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("Download() wrote %d bytes, content match = %v", n, bytes.Equal(buf.Bytes(), content))
+	}
+	if want := []string{"", "bytes=50000-", "bytes=75000-"}; strings.Join(*ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("Range headers = %q, want %q", *ranges, want)
+	}
+	if progress.completed != int64(len(content)) || progress.total != int64(len(content)) || !progress.finished {
+		t.Errorf("progress = %d/%d finished=%v", progress.completed, progress.total, progress.finished)
+	}
+}
+
+func TestDownloadGivesUpAfterMaxResumes(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1000)
+	server, _ := downloadServer(t, content, maxDownloadResumes+1)
+
+	client := NewClient(server.URL)
+	var buf bytes.Buffer
+	_, err := client.Download(context.Background(), "/report", &buf, nil)
+	if !errors.Is(err, ErrIncompleteDownload) {
+		t.Fatalf("Download() error = %v, want ErrIncompleteDownload", err)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 4096)
+
+	t.Run("resumes partial file", func(t *testing.T) {
+		server, ranges := downloadServer(t, content, 0)
+		filename := filepath.Join(t.TempDir(), "report.csv")
+		if err := os.WriteFile(filename+".part", content[:1000], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename+".part.etag", []byte(`"v1"`+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		client := NewClient(server.URL)
+		progress := &countingProgress{}
+		n, err := client.DownloadFile(context.Background(), "/report", filename, progress)
+		if err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		got, _ := os.ReadFile(filename)
+		if n != int64(len(content)) || !bytes.Equal(got, content) {
+			t.Errorf("DownloadFile() = %d, file has %d bytes", n, len(got))
+		}
+		if (*ranges)[0] != "bytes=1000-" {
+			t.Errorf("Range = %q, want bytes=1000-", (*ranges)[0])
+		}
+		for _, name := range []string{filename + ".part", filename + ".part.etag"} {
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("%s still exists: %v", name, err)
+			}
+		}
+		if progress.completed != int64(len(content)) {
+			t.Errorf("progress completed = %d, want %d", progress.completed, len(content))
+		}
+	})
+
+	t.Run("partial file without validator restarts", func(t *testing.T) {
+		server, ranges := downloadServer(t, content, 0)
+		filename := filepath.Join(t.TempDir(), "report.csv")
+		os.WriteFile(filename+".part", []byte("OLDOLD"), 0o644)
+
+		client := NewClient(server.URL)
+		if _, err := client.DownloadFile(context.Background(), "/report", filename, nil); err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		if got, _ := os.ReadFile(filename); !bytes.Equal(got, content) {
+			t.Errorf("file has %d bytes, want %d", len(got), len(content))
+		}
+		if (*ranges)[0] != "" {
+			t.Errorf("Range = %q, want none", (*ranges)[0])
+		}
+	})
+
+	t.Run("server ignores range", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		}))
+		defer server.Close()
+		filename := filepath.Join(t.TempDir(), "report.csv")
+		os.WriteFile(filename+".part", content[:1000], 0o644)
+
+		client := NewClient(server.URL)
+		if _, err := client.DownloadFile(context.Background(), "/report", filename, nil); err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		if got, _ := os.ReadFile(filename); !bytes.Equal(got, content) {
+			t.Errorf("file has %d bytes, want %d", len(got), len(content))
+		}
+	})
+
+	t.Run("already complete", func(t *testing.T) {
+		server, _ := downloadServer(t, content, 0)
+		filename := filepath.Join(t.TempDir(), "report.csv")
+		os.WriteFile(filename+".part", content, 0o644)
+		os.WriteFile(filename+".part.etag", []byte(`"v1"`), 0o644)
+
+		client := NewClient(server.URL)
+		if _, err := client.DownloadFile(context.Background(), "/report", filename, nil); err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		if got, _ := os.ReadFile(filename); !bytes.Equal(got, content) {
+			t.Errorf("file has %d bytes, want %d", len(got), len(content))
+		}
+	})
+
+	t.Run("error leaves no files", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "report not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+		dir := t.TempDir()
+
+		client := NewClient(server.URL)
+		_, err := client.DownloadFile(context.Background(), "/report", filepath.Join(dir, "report.csv"), nil)
+		if err == nil || !strings.Contains(err.Error(), "status 404") {
+			t.Fatalf("DownloadFile() error = %v, want status 404", err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("directory has %d entries, want none", len(entries))
+		}
+	})
+}
+
+func TestDownloadContentChangedDuringResume(t *testing.T) {
+	v1 := bytes.Repeat([]byte("a"), 10000)
+	v2 := bytes.Repeat([]byte("b"), 12000)
+	var mu sync.Mutex
+	var ifRanges []string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		mu.Unlock()
+
+		if n == 1 {
+			// Version 1 is cut off halfway through
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(v1)))
+			w.Write(v1[:len(v1)/2])
+			return
+		}
+		// and replaced before the resume, so If-Range sends all of version 2
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "report.csv", time.Time{}, bytes.NewReader(v2))
+	}))
+	defer server.Close()
+
+	t.Run("file", func(t *testing.T) {
+		mu.Lock()
+		requests, ifRanges = 0, nil
+		mu.Unlock()
+		filename := filepath.Join(t.TempDir(), "report.csv")
+		client := NewClient(server.URL)
+		progress := &countingProgress{}
+		n, err := client.DownloadFile(context.Background(), "/report", filename, progress)
+		if err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		if got, _ := os.ReadFile(filename); n != int64(len(v2)) || !bytes.Equal(got, v2) {
+			t.Errorf("DownloadFile() = %d, file is not version 2", n)
+		}
+		if len(ifRanges) != 2 || ifRanges[1] != `"v1"` {
+			t.Errorf("If-Range headers = %q, want the first response's ETag on resume", ifRanges)
+		}
+		if progress.completed != int64(len(v2)) {
+			t.Errorf("progress completed = %d, want %d", progress.completed, len(v2))
+		}
+	})
+
+	t.Run("writer", func(t *testing.T) {
+		mu.Lock()
+		requests, ifRanges = 0, nil
+		mu.Unlock()
+		client := NewClient(server.URL)
+		var buf bytes.Buffer
+		if _, err := client.Download(context.Background(), "/report", &buf, nil); !errors.Is(err, ErrDownloadChanged) {
+			t.Fatalf("Download() error = %v, want ErrDownloadChanged", err)
+		}
+	})
+}
+
+func TestDownloadFileChangedBetweenRuns(t *testing.T) {
+	var mu sync.Mutex
+	content, etag, interrupt := []byte("OLDOLDOLDOLD"), `"v1"`, true
+	var ifRanges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		w.Header().Set("ETag", etag)
+		if interrupt {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:6])
+			return
+		}
+		http.ServeContent(w, r, "report.csv", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	filename := filepath.Join(t.TempDir(), "report.csv")
+	client := NewClient(server.URL)
+
+	// The first run is cut off and leaves OLDOLD with its ETag
+	if _, err := client.DownloadFile(context.Background(), "/report", filename, nil); !errors.Is(err, ErrIncompleteDownload) {
+		t.Fatalf("first DownloadFile() error = %v, want ErrIncompleteDownload", err)
+	}
+	if saved, _ := os.ReadFile(filename + ".part.etag"); strings.TrimSpace(string(saved)) != `"v1"` {
+		t.Fatalf("saved validator = %q, want \"v1\"", saved)
+	}
+
+	// The content changes before the next run
+	mu.Lock()
+	content, etag, interrupt, ifRanges = []byte("NEWNEWNEWNEW"), `"v2"`, false, nil
+	mu.Unlock()
+	if _, err := client.DownloadFile(context.Background(), "/report", filename, nil); err != nil {
+		t.Fatalf("second DownloadFile() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filename); string(got) != "NEWNEWNEWNEW" {
+		t.Errorf("file = %q, want NEWNEWNEWNEW", got)
+	}
+	if len(ifRanges) != 1 || ifRanges[0] != `"v1"` {
+		t.Errorf("If-Range headers = %q, want the saved ETag", ifRanges)
+	}
+	if _, err := os.Stat(filename + ".part.etag"); !os.IsNotExist(err) {
+		t.Errorf("validator file still exists: %v", err)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value        string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */1000", 0, 1000, true},
+		{"items 0-1/2", 0, 0, false},
+		{"bytes 100-199", 0, 0, false},
+		{"bytes x-199/1000", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.value)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v, want %d, %d, %v", tt.value, start, total, ok, tt.start, tt.total, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// dryRunHeader marks responses to requests captured in dry-run mode
+const dryRunHeader = "X-Dry-Run"
+
+// MutationFunc reports whether a request changes data on the server. It
+// decides which requests DryRun captures and AuditLog records, for APIs that
+// make changes with GET requests.
+type MutationFunc func(req *http.Request) bool
+
+// MethodIsMutation is the default MutationFunc: requests other than GET and
+// HEAD are mutations
+func MethodIsMutation(req *http.Request) bool {
+	return req.Method != http.MethodGet && req.Method != http.MethodHead
+}
+
+// DryRun captures requests instead of sending them, so that a program can
+// preview the changes it would make. Set it with SetDryRun.
+type DryRun struct {
+	// ExecuteReads sends requests that are not mutations and session logins
+	// as usual, so a preview can look up existing data
+	ExecuteReads bool
+	// IsMutation reports whether a request changes data on the server. The
+	// default is MethodIsMutation.
+	IsMutation MutationFunc
+	// Respond returns the response to a captured request. Without it captured
+	// requests succeed with 204 No Content.
+	Respond func(req CapturedRequest) DryRunResponse
+
+	mu       sync.Mutex
+	captured []CapturedRequest
+}
+
+// CapturedRequest is a request captured in dry-run mode, with credentials
+// redacted
+type CapturedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	// Body is the request body, decompressed if it was compressed
+	Body []byte
+}
+
+// DryRunResponse is a canned response to a captured request
+type DryRunResponse struct {
+	// StatusCode defaults to 200
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// SetDryRun enables dry-run mode. Requests are captured by dryRun and
+// answered without reaching the server; middleware, hooks and logging still
+// see them. A nil dryRun sends requests again.
+func (c *Client) SetDryRun(dryRun *DryRun) {
+	c.dryRun = dryRun
+}
+
+// Requests returns the requests captured so far
+func (d *DryRun) Requests() []CapturedRequest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]CapturedRequest(nil), d.captured...)
+}
+
+// executes reports whether req is sent despite dry-run mode
+func (d *DryRun) executes(req *http.Request) bool {
+	if !d.ExecuteReads {
+		return false
+	}
+	if req.Context().Value(sessionRequestKey{}) != nil {
+		return true
+	}
+	if d.IsMutation != nil {
+		return !d.IsMutation(req)
+	}
+	return !MethodIsMutation(req)
+}
+
+// dryRunTransport returns a transport that captures requests and answers
+// them instead of sending them to next
+func (c *Client) dryRunTransport(next http.RoundTripper) http.RoundTripper {
+	dryRun := c.dryRun
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if dryRun.executes(req) {
+			return next.RoundTrip(req)
+		}
+
+		captured, err := captureRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		dryRun.mu.Lock()
+		dryRun.captured = append(dryRun.captured, captured)
+		dryRun.mu.Unlock()
+		c.logDryRun(req.Context(), captured)
+
+		canned := DryRunResponse{StatusCode: http.StatusNoContent}
+		if dryRun.Respond != nil {
+			canned = dryRun.Respond(captured)
+		}
+		return canned.response(req), nil
+	})
+}
+
+// captureRequest reads and redacts a request
+func captureRequest(req *http.Request) (CapturedRequest, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return CapturedRequest{}, err
+	}
+	return CapturedRequest{
+		Method: req.Method,
+		URL:    redactURL(req.Context(), req.URL.String()),
+		Header: redactHeaders(req.Context(), req.Header),
+		Body:   redactFormBody(req, decodeRequestBody(req, body)),
+	}, nil
+}
+
+// response builds the HTTP response for a canned response
+func (r DryRunResponse) response(req *http.Request) *http.Response {
+	status := r.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(dryRunHeader, "true")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
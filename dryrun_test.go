@@ -0,0 +1,142 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`<USERS><USER>alice</USER></USERS>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetBasicAuth("admin", "secret")
+	var logs bytes.Buffer
+	client.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	dryRun := &DryRun{ExecuteReads: true}
+	client.SetDryRun(dryRun)
+	ctx := context.Background()
+
+	var users struct {
+		Users []string `xml:"USER"`
+	}
+	if err := client.Get(ctx, "/msp/user_list.php", nil, &users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(users.Users) != 1 || calls.Load() != 1 {
+		t.Errorf("GET result = %v after %d calls, want it executed", users.Users, calls.Load())
+	}
+
+	var result struct {
+		Message string `xml:"MESSAGE"`
+	}
+	err := client.Post(ctx, "/msp/user.php?action=add&user_login=bob&password=hunter2", []byte(`{"role":"reader"}`), &result)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, POST reached the server", calls.Load())
+	}
+
+	requests := dryRun.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("captured %d requests, want 1", len(requests))
+	}
+	captured := requests[0]
+	if captured.Method != http.MethodPost || string(captured.Body) != `{"role":"reader"}` {
+		t.Errorf("captured = %s %s", captured.Method, captured.Body)
+	}
+	if strings.Contains(captured.URL, "hunter2") || !strings.Contains(captured.URL, "user_login=bob") {
+		t.Errorf("captured URL = %s, want password redacted", captured.URL)
+	}
+	if got := captured.Header.Get("Authorization"); got != redactedValue {
+		t.Errorf("captured Authorization = %q, want redacted", got)
+	}
+	if !strings.Contains(logs.String(), "dry run, request not sent") {
+		t.Errorf("logs = %s, want the captured request", logs.String())
+	}
+}
+
+func TestDryRunCannedResponse(t *testing.T) {
+	client := NewClient("http://unused.invalid")
+	client.SetDryRun(&DryRun{
+		Respond: func(req CapturedRequest) DryRunResponse {
+			if req.Method == http.MethodGet {
+				return DryRunResponse{StatusCode: http.StatusNotFound}
+			}
+			return DryRunResponse{Body: []byte(`<RESPONSE><MESSAGE>user bob would be added</MESSAGE></RESPONSE>`)}
+		},
+	})
+
+	var result struct {
+		Message string `xml:"MESSAGE"`
+	}
+	ctx := context.Background()
+	if err := client.Post(ctx, "/msp/user.php", []byte("action=add"), &result); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if result.Message != "user bob would be added" {
+		t.Errorf("result = %+v, want the canned response", result)
+	}
+
+	// GETs are captured too unless ExecuteReads is set
+	if err := client.Get(ctx, "/msp/user_list.php", nil, nil); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("Get() error = %v, want canned 404", err)
+	}
+}
+
+func TestDryRunDecompressesCapturedBody(t *testing.T) {
+	dryRun := &DryRun{}
+	client := NewClient("http://unused.invalid")
+	client.SetDryRun(dryRun)
+	client.SetCompression(&CompressionConfig{RequestEncoding: EncodingGzip})
+
+	if err := client.Post(context.Background(), "/", []byte("<USER/>"), nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if body := string(dryRun.Requests()[0].Body); body != "<USER/>" {
+		t.Errorf("captured body = %q, want it decompressed", body)
+	}
+}
+
+func TestDryRunIsMutation(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`<USER_LIST_OUTPUT/>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	dryRun := &DryRun{
+		ExecuteReads: true,
+		IsMutation: func(req *http.Request) bool {
+			return req.URL.Path == "/msp/user.php"
+		},
+	}
+	client.SetDryRun(dryRun)
+	ctx := context.Background()
+
+	if err := client.Get(ctx, "/msp/user_list.php", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := client.Get(ctx, "/msp/user.php?action=add&user_login=bob", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want only the list call sent", calls.Load())
+	}
+	if requests := dryRun.Requests(); len(requests) != 1 || !strings.Contains(requests[0].URL, "action=add") {
+		t.Errorf("captured = %+v, want the GET that adds a user", requests)
+	}
+}
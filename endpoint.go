@@ -0,0 +1,276 @@
+package godefaultapi
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BodyEncoding is how an endpoint encodes its request body
+type BodyEncoding int
+
+const (
+	// BodyNone sends no request body
+	BodyNone BodyEncoding = iota
+	// BodyJSON sends the request encoded as JSON
+	BodyJSON
+	// BodyXML sends the request encoded as XML
+	BodyXML
+	// BodyForm sends the request fields tagged `form` as a URL encoded form
+	BodyForm
+)
+
+// Endpoint declares a typed API operation. Fields of Req tagged
+// `path:"name"` fill {name} placeholders in Path as with ExpandPath, fields
+// tagged `query:"name"` are added to the query string and, with BodyForm,
+// fields tagged `form:"name"` make up the body. The omitempty tag option skips zero
+// values and the comma option sends slices as one comma separated value.
+// BodyJSON and BodyXML encode all of Req, so tag its path and query fields
+// `json:"-"` or `xml:"-"` to leave them out of the body. Use struct{} as
+// Req or Resp for operations without parameters or without a response body.
+//
+//	var listScans = godefaultapi.Endpoint[ScanListRequest, ScanListOutput]{
+//		Name:   "list scans",
+//		Method: http.MethodGet,
+//		Path:   "/api/2.0/fo/scan/?action=list",
+//	}
+type Endpoint[Req, Resp any] struct {
+	// Name identifies the endpoint in errors, e.g. "list scans"
+	Name string
+	// Method is the HTTP method, GET if empty
+	Method string
+	// Path is the path template, e.g. "/qps/rest/2.0/get/am/hostasset/{id}".
+	// It may include a fixed query string such as "?action=list".
+	Path string
+	// Body is how the request body is encoded
+	Body BodyEncoding
+	// ResponseType overrides the client's response type, if set
+	ResponseType ContentType
+}
+
+// Call performs the operation with client and decodes the response
+func (e Endpoint[Req, Resp]) Call(ctx context.Context, client *Client, req Req) (Resp, error) {
+	var resp Resp
+	path, body, contentType, err := e.encode(req)
+	if err != nil {
+		return resp, fmt.Errorf("error encoding %s request: %w", e.name(), err)
+	}
+
+	// The path is already expanded, so ignore any PathParams in ctx
+	ctx = WithPathParams(WithRoute(ctx, routeFor(context.Background(), e.Path)), nil)
+	if contentType != "" {
+		ctx = withHeaders(ctx, http.Header{"Content-Type": {contentType}})
+	}
+	if e.ResponseType != "" {
+		ctx = withResponseType(ctx, e.ResponseType)
+	}
+
+	var result interface{} = &resp
+	if _, ok := any(resp).(struct{}); ok {
+		result = nil
+	}
+	if err := client.doRequest(ctx, e.method(), path, body, result); err != nil {
+		return resp, fmt.Errorf("error calling %s: %w", e.name(), err)
+	}
+	return resp, nil
+}
+
+// method returns the HTTP method of the endpoint
+func (e Endpoint[Req, Resp]) method() string {
+	if e.Method == "" {
+		return http.MethodGet
+	}
+	return e.Method
+}
+
+// name returns the name of the endpoint for errors
+func (e Endpoint[Req, Resp]) name() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.method() + " " + e.Path
+}
+
+// encode builds the request path, body and body content type for req
+func (e Endpoint[Req, Resp]) encode(req Req) (path string, body []byte, contentType string, err error) {
+	params, err := collectParams(req)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	path, err = ExpandPath(e.Path, params.path)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if query := params.query.Encode(); query != "" {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path += separator + query
+	}
+
+	switch e.Body {
+	case BodyNone:
+	case BodyJSON:
+		if body, err = json.Marshal(req); err != nil {
+			return "", nil, "", fmt.Errorf("error encoding JSON body: %w", err)
+		}
+		contentType = string(ContentTypeJSON)
+	case BodyXML:
+		if body, err = xml.Marshal(req); err != nil {
+			return "", nil, "", fmt.Errorf("error encoding XML body: %w", err)
+		}
+		contentType = string(ContentTypeXML)
+	case BodyForm:
+		body = []byte(params.form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	default:
+		return "", nil, "", fmt.Errorf("unsupported body encoding: %d", e.Body)
+	}
+	return path, body, contentType, nil
+}
+
+// requestParams are the parameters of an endpoint request
+type requestParams struct {
+	path  map[string]string
+	query url.Values
+	form  url.Values
+}
+
+// collectParams reads the path, query and form parameters from the tagged
+// fields of v, a struct or pointer to a struct
+func collectParams(v interface{}) (*requestParams, error) {
+	params := &requestParams{
+		path:  make(map[string]string),
+		query: make(url.Values),
+		form:  make(url.Values),
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return params, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return params, nil
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		for _, kind := range []string{"path", "query", "form"} {
+			tag, ok := field.Tag.Lookup(kind)
+			if !ok || tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = field.Name
+			}
+			fv := rv.Field(i)
+			if hasTagOption(options, "omitempty") && fv.IsZero() {
+				continue
+			}
+			values, err := formatParam(fv)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			if hasTagOption(options, "comma") || kind == "path" {
+				values = []string{strings.Join(values, ",")}
+			}
+
+			switch kind {
+			case "path":
+				params.path[name] = values[0]
+			case "query":
+				params.query[name] = append(params.query[name], values...)
+			case "form":
+				params.form[name] = append(params.form[name], values...)
+			}
+		}
+	}
+	return params, nil
+}
+
+// hasTagOption reports whether a comma separated tag option list contains option
+func hasTagOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// formatParam formats a field value as parameter values. Slices produce one
+// value per element.
+func formatParam(v reflect.Value) ([]string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return []string{x.Format(time.RFC3339)}, nil
+	case fmt.Stringer:
+		return []string{x.String()}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return []string{v.String()}, nil
+	case reflect.Bool:
+		return []string{strconv.FormatBool(v.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())}, nil
+	case reflect.Slice, reflect.Array:
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			elem, err := formatParam(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, elem...)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %s", v.Type())
+	}
+}
+
+// responseTypeKey is the context key for a per-call response type
+type responseTypeKey struct{}
+
+// withResponseType returns a context whose calls expect responses in
+// contentType instead of the client's response type
+func withResponseType(ctx context.Context, contentType ContentType) context.Context {
+	return context.WithValue(ctx, responseTypeKey{}, contentType)
+}
+
+// responseTypeFor returns the response type for a call
+func (c *Client) responseTypeFor(ctx context.Context) ContentType {
+	if contentType, ok := ctx.Value(responseTypeKey{}).(ContentType); ok {
+		return contentType
+	}
+	return c.responseType
+}
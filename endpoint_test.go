@@ -0,0 +1,177 @@
+package godefaultapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scanListRequest struct {
+	States    []string  `query:"state,comma"`
+	Since     time.Time `query:"launched_after_datetime,omitempty"`
+	ShowAGs   bool      `query:"show_ags,omitempty"`
+	unexposed string
+}
+
+type scanListOutput struct {
+	Scans []struct {
+		Ref string `xml:"REF"`
+	} `xml:"RESPONSE>SCAN_LIST>SCAN"`
+}
+
+type hostAssetRequest struct {
+	ID int `path:"id" json:"-"`
+}
+
+type hostAsset struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type addUserRequest struct {
+	Login     string   `form:"login" json:"login"`
+	FirstName string   `form:"first_name" json:"first_name"`
+	Groups    []string `form:"asset_groups,comma" json:"-"`
+	Title     string   `form:"title,omitempty" json:"-"`
+	Send      bool     `query:"send_email" json:"-"`
+}
+
+func TestEndpointCall(t *testing.T) {
+	type received struct {
+		method, uri, contentType, accept, body string
+	}
+	var got received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = received{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), r.Header.Get("Accept"), string(body)}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/2.0/fo/scan/"):
+			w.Write([]byte(`<SCAN_LIST_OUTPUT><RESPONSE><SCAN_LIST><SCAN><REF>scan/1.2</REF></SCAN></SCAN_LIST></RESPONSE></SCAN_LIST_OUTPUT>`))
+		case strings.HasPrefix(r.URL.Path, "/qps/"):
+			w.Write([]byte(`{"id":42,"name":"web01"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var routes []string
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			info, _ := RequestInfoFromContext(req.Context())
+			routes = append(routes, info.Route)
+			return next.RoundTrip(req)
+		})
+	})
+	ctx := context.Background()
+
+	t.Run("GET with query parameters", func(t *testing.T) {
+		listScans := Endpoint[scanListRequest, scanListOutput]{
+			Name: "list scans",
+			Path: "/api/2.0/fo/scan/?action=list",
+		}
+		since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		out, err := listScans.Call(ctx, client, scanListRequest{States: []string{"Finished", "Running"}, Since: since})
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if len(out.Scans) != 1 || out.Scans[0].Ref != "scan/1.2" {
+			t.Errorf("Call() = %+v", out)
+		}
+		want := "/api/2.0/fo/scan/?action=list&launched_after_datetime=2024-05-01T00%3A00%3A00Z&state=Finished%2CRunning"
+		if got.method != http.MethodGet || got.uri != want {
+			t.Errorf("request = %s %s, want GET %s", got.method, got.uri, want)
+		}
+		if got.body != "" {
+			t.Errorf("body = %q, want none", got.body)
+		}
+	})
+
+	t.Run("path parameters and response type", func(t *testing.T) {
+		getAsset := Endpoint[hostAssetRequest, hostAsset]{
+			Path:         "/qps/rest/2.0/get/am/hostasset/{id}",
+			ResponseType: ContentTypeJSON,
+		}
+		asset, err := getAsset.Call(ctx, client, hostAssetRequest{ID: 42})
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if asset.ID != 42 || asset.Name != "web01" {
+			t.Errorf("Call() = %+v", asset)
+		}
+		if got.uri != "/qps/rest/2.0/get/am/hostasset/42" || got.accept != string(ContentTypeJSON) {
+			t.Errorf("request = %s with Accept %q", got.uri, got.accept)
+		}
+		if routes[len(routes)-1] != "/qps/rest/2.0/get/am/hostasset/{id}" {
+			t.Errorf("route = %q, want the template", routes[len(routes)-1])
+		}
+	})
+
+	t.Run("form body", func(t *testing.T) {
+		addUser := Endpoint[addUserRequest, struct{}]{
+			Method: http.MethodPost,
+			Path:   "/msp/user.php?action=add",
+			Body:   BodyForm,
+		}
+		_, err := addUser.Call(ctx, client, addUserRequest{Login: "jdoe", FirstName: "J D", Groups: []string{"a", "b"}})
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if got.uri != "/msp/user.php?action=add&send_email=false" {
+			t.Errorf("uri = %q", got.uri)
+		}
+		if got.contentType != "application/x-www-form-urlencoded" || got.body != "asset_groups=a%2Cb&first_name=J+D&login=jdoe" {
+			t.Errorf("body = %q with Content-Type %q", got.body, got.contentType)
+		}
+	})
+
+	t.Run("JSON body", func(t *testing.T) {
+		addUser := Endpoint[*addUserRequest, struct{}]{
+			Method: http.MethodPost,
+			Path:   "/users",
+			Body:   BodyJSON,
+		}
+		if _, err := addUser.Call(ctx, client, &addUserRequest{Login: "jdoe"}); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		var body map[string]string
+		if err := json.Unmarshal([]byte(got.body), &body); err != nil || len(body) != 2 || body["login"] != "jdoe" {
+			t.Errorf("body = %q, err = %v", got.body, err)
+		}
+		if got.contentType != string(ContentTypeJSON) || got.uri != "/users?send_email=false" {
+			t.Errorf("request = %s with Content-Type %q", got.uri, got.contentType)
+		}
+	})
+}
+
+func TestEndpointErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such asset", http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	missing := Endpoint[struct{}, struct{}]{Path: "/assets/{id}"}
+	if _, err := missing.Call(context.Background(), client, struct{}{}); err == nil || !strings.Contains(err.Error(), `missing path parameter "id"`) {
+		t.Errorf("Call() error = %v, want missing path parameter", err)
+	}
+
+	unsupported := Endpoint[struct {
+		Filter map[string]string `query:"filter"`
+	}, struct{}]{Path: "/assets"}
+	if _, err := unsupported.Call(context.Background(), client, struct {
+		Filter map[string]string `query:"filter"`
+	}{}); err == nil || !strings.Contains(err.Error(), "unsupported parameter type") {
+		t.Errorf("Call() error = %v, want unsupported parameter type", err)
+	}
+
+	getAsset := Endpoint[hostAssetRequest, hostAsset]{Name: "get host asset", Path: "/assets/{id}"}
+	_, err := getAsset.Call(context.Background(), client, hostAssetRequest{ID: 1})
+	if err == nil || !strings.HasPrefix(err.Error(), "error calling get host asset: request failed with status 404") {
+		t.Errorf("Call() error = %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
@@ -309,6 +310,7 @@ func main() {
 	city := flag.String("city", "Milwaukee", "City")
 	zipcode := flag.String("zipcode", "53202", "Zipcode")
 	state := flag.String("state", "Wisconsin", "State")
+	dryRun := flag.Bool("dryrun", false, "Preview the users that would be added without creating them")
 
 	flag.Parse()
 
@@ -326,6 +328,22 @@ func main() {
 	client.SetRequestType(godefaultapi.ContentTypeJSON)
 	client.SetResponseType(godefaultapi.ContentTypeXML)
 	client.SetHeader("X-Requested-With", "GOQualysAPI")
+	if *dryRun {
+		// Look up existing users, but only pretend to add new ones
+		client.SetDryRun(&godefaultapi.DryRun{
+			ExecuteReads: true,
+			// Users are added with a GET to user.php
+			IsMutation: func(req *http.Request) bool {
+				return req.URL.Path == "/msp/user.php"
+			},
+			Respond: func(req godefaultapi.CapturedRequest) godefaultapi.DryRunResponse {
+				fmt.Printf("\nWould send %s %s\n", req.Method, req.URL)
+				return godefaultapi.DryRunResponse{
+					Body: []byte(`<USER_OUTPUT><RETURN status="SUCCESS"><MESSAGE>dry-run</MESSAGE></RETURN></USER_OUTPUT>`),
+				}
+			},
+		})
+	}
 
 	if err := processCSV(client, *inputFile, *doemail, *address1, *city, *zipcode, *state); err != nil {
 		log.Fatal(err)
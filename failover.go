@@ -0,0 +1,179 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultFailoverCooldown is how long a failed base URL is avoided
+const defaultFailoverCooldown = 30 * time.Second
+
+// baseURLPool tracks the health of a client's base URLs
+type baseURLPool struct {
+	mu        sync.Mutex
+	urls      []string
+	downUntil []time.Time
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// SetBaseURLs sets the base URLs requests are sent to, in order of
+// preference. When a base URL fails with a connection error or a 502, 503 or
+// 504 response the attempt is repeated against the next one, and the failed
+// URL is avoided until its cooldown passes. Calls that are not safe to re-send
+// are only repeated if the failed attempt cannot have been processed, see
+// WithRetrySafe. The first URL replaces the one given to NewClient.
+func (c *Client) SetBaseURLs(urls ...string) {
+	if len(urls) == 0 {
+		return
+	}
+	c.baseURL = urls[0]
+	if len(urls) == 1 {
+		c.basePool = nil
+		return
+	}
+
+	cooldown := defaultFailoverCooldown
+	if c.basePool != nil {
+		cooldown = c.basePool.cooldown
+	}
+	c.basePool = &baseURLPool{
+		urls:      append([]string(nil), urls...),
+		downUntil: make([]time.Time, len(urls)),
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// SetFailoverCooldown sets how long a failed base URL is avoided before it
+// is tried again. It applies once several base URLs are set.
+func (c *Client) SetFailoverCooldown(cooldown time.Duration) {
+	if c.basePool != nil {
+		c.basePool.mu.Lock()
+		c.basePool.cooldown = cooldown
+		c.basePool.mu.Unlock()
+	}
+}
+
+// BaseURLs returns the client's base URLs in order of preference
+func (c *Client) BaseURLs() []string {
+	if c.basePool == nil {
+		return []string{c.baseURL}
+	}
+	return append([]string(nil), c.basePool.urls...)
+}
+
+// pickBaseURL returns the base URL for the next attempt
+func (c *Client) pickBaseURL() string {
+	if c.basePool == nil {
+		return c.baseURL
+	}
+	return c.basePool.pick()
+}
+
+// pick returns the first healthy base URL, or the one that recovers soonest
+// if all have failed
+func (p *baseURLPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	soonest := 0
+	for i, until := range p.downUntil {
+		if !until.After(now) {
+			return p.urls[i]
+		}
+		if until.Before(p.downUntil[soonest]) {
+			soonest = i
+		}
+	}
+	return p.urls[soonest]
+}
+
+// markDown avoids base until the cooldown passes
+func (p *baseURLPool) markDown(base string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, url := range p.urls {
+		if url == base {
+			p.downUntil[i] = p.now().Add(p.cooldown)
+		}
+	}
+}
+
+// markUp records that base is healthy
+func (p *baseURLPool) markUp(base string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, url := range p.urls {
+		if url == base {
+			p.downUntil[i] = time.Time{}
+		}
+	}
+}
+
+// failover records the outcome of an attempt against base and reports
+// whether the attempt should be repeated against another base URL. failovers
+// is the number of failovers already made in the call.
+func (c *Client) failover(ctx context.Context, base string, resp *http.Response, err error, failovers int) bool {
+	if c.basePool == nil {
+		return false
+	}
+
+	if err != nil {
+		// Errors from building the request or from the caller's context say
+		// nothing about the server
+		var sendErr *sendError
+		if !errors.As(err, &sendErr) || ctx.Err() != nil {
+			return false
+		}
+	} else {
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			c.basePool.markUp(base)
+			return false
+		}
+	}
+
+	c.basePool.markDown(base)
+	return failovers < len(c.basePool.urls)-1
+}
+
+// idempotentMethod reports whether a request with method may be sent again
+// after an attempt that may have reached the server
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// mayHaveBeenProcessed reports whether a failed attempt may have reached the
+// server. Refused connections and 503 responses were not processed, and
+// neither were errors from building the request.
+func mayHaveBeenProcessed(resp *http.Response, err error) bool {
+	if err != nil {
+		var sendErr *sendError
+		if !errors.As(err, &sendErr) {
+			return false
+		}
+		var opErr *net.OpError
+		return !errors.As(err, &opErr) || opErr.Op != "dial"
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout
+}
+
+// sendError marks an error from sending a request, as opposed to
+// building it
+type sendError struct {
+	err error
+}
+
+func (e *sendError) Error() string { return e.err.Error() }
+func (e *sendError) Unwrap() error { return e.err }
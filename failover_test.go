@@ -0,0 +1,155 @@
+package godefaultapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer responds with status and counts requests
+func countingServer(t *testing.T, status *atomic.Int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte("<OK/>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestBaseURLFailover(t *testing.T) {
+	var primaryStatus, secondaryStatus atomic.Int32
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	secondaryStatus.Store(http.StatusOK)
+	primary, primaryCalls := countingServer(t, &primaryStatus)
+	secondary, secondaryCalls := countingServer(t, &secondaryStatus)
+
+	client := NewClient("http://unused.invalid")
+	client.SetBaseURLs(primary.URL, secondary.URL)
+	now := time.Now()
+	client.basePool.now = func() time.Time { return now }
+
+	var attempts []string
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts = append(attempts, req.URL.Host)
+			return next.RoundTrip(req)
+		})
+	})
+
+	ctx := context.Background()
+	if err := client.Get(ctx, "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if primaryCalls.Load() != 1 || secondaryCalls.Load() != 1 {
+		t.Errorf("calls = %d, %d, want 1, 1", primaryCalls.Load(), secondaryCalls.Load())
+	}
+
+	// The failed primary is avoided during its cooldown
+	if err := client.Get(ctx, "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if primaryCalls.Load() != 1 || secondaryCalls.Load() != 2 {
+		t.Errorf("calls during cooldown = %d, %d, want 1, 2", primaryCalls.Load(), secondaryCalls.Load())
+	}
+
+	// and tried again once it passes
+	primaryStatus.Store(http.StatusOK)
+	now = now.Add(defaultFailoverCooldown)
+	if err := client.Get(ctx, "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if primaryCalls.Load() != 2 || secondaryCalls.Load() != 2 {
+		t.Errorf("calls after cooldown = %d, %d, want 2, 2", primaryCalls.Load(), secondaryCalls.Load())
+	}
+	if len(attempts) != 4 {
+		t.Errorf("attempts = %v, want 4", attempts)
+	}
+}
+
+func TestBaseURLFailoverConnectionError(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	up, calls := countingServer(t, &status)
+
+	client := NewClient(down.URL)
+	if err := client.SetPlatforms(down.URL, up.URL); err != nil {
+		t.Fatalf("SetPlatforms() error = %v", err)
+	}
+	if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestBaseURLFailoverGivesUp(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusBadGateway)
+	first, firstCalls := countingServer(t, &status)
+	second, secondCalls := countingServer(t, &status)
+
+	client := NewClient(first.URL)
+	client.SetBaseURLs(first.URL, second.URL)
+	err := client.Get(context.Background(), "/", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 502") {
+		t.Fatalf("Get() error = %v, want status 502", err)
+	}
+	if firstCalls.Load() != 1 || secondCalls.Load() != 1 {
+		t.Errorf("calls = %d, %d, want 1, 1", firstCalls.Load(), secondCalls.Load())
+	}
+}
+
+func TestBaseURLNoFailoverOnInternalServerError(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	first, _ := countingServer(t, &status)
+	second, secondCalls := countingServer(t, &status)
+
+	client := NewClient(first.URL)
+	client.SetBaseURLs(first.URL, second.URL)
+	if err := client.Get(context.Background(), "/", nil, nil); err == nil {
+		t.Fatal("Get() error = nil, want status 500")
+	}
+	if secondCalls.Load() != 0 {
+		t.Errorf("secondary called %d times for a 500, want 0", secondCalls.Load())
+	}
+}
+
+func TestBaseURLFailoverNonIdempotent(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusGatewayTimeout)
+	first, firstCalls := countingServer(t, &status)
+	second, secondCalls := countingServer(t, &status)
+
+	// A 504 may come after the first server processed the request
+	client := NewClient(first.URL)
+	client.SetBaseURLs(first.URL, second.URL)
+	if err := client.Post(context.Background(), "/", nil, nil); err == nil {
+		t.Fatal("Post() error = nil, want status 504")
+	}
+	if firstCalls.Load() != 1 || secondCalls.Load() != 0 {
+		t.Errorf("calls = %d, %d, want 1, 0", firstCalls.Load(), secondCalls.Load())
+	}
+
+	// A refused connection cannot have been processed
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	status.Store(http.StatusOK)
+	client.SetBaseURLs(down.URL, second.URL)
+	if err := client.Post(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if secondCalls.Load() != 1 {
+		t.Errorf("secondary calls = %d, want 1", secondCalls.Load())
+	}
+}
@@ -0,0 +1,254 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultKind is a kind of injected failure
+type FaultKind int
+
+const (
+	// FaultLatency delays the request
+	FaultLatency FaultKind = iota
+	// FaultConnectionReset fails the request with a connection reset error
+	FaultConnectionReset
+	// FaultRateLimit responds 429 with a rate limit reset header
+	FaultRateLimit
+	// FaultServerError responds with a 5xx status
+	FaultServerError
+	// FaultTruncatedBody cuts the response body short with an unexpected EOF
+	FaultTruncatedBody
+	// FaultMalformedXML injects mismatched tags and an invalid entity into the
+	// response body so that it no longer parses
+	FaultMalformedXML
+)
+
+// String returns the name of the fault kind
+func (k FaultKind) String() string {
+	switch k {
+	case FaultLatency:
+		return "latency"
+	case FaultConnectionReset:
+		return "connection-reset"
+	case FaultRateLimit:
+		return "rate-limit"
+	case FaultServerError:
+		return "server-error"
+	case FaultTruncatedBody:
+		return "truncated-body"
+	case FaultMalformedXML:
+		return "malformed-xml"
+	default:
+		return fmt.Sprintf("FaultKind(%d)", int(k))
+	}
+}
+
+// Fault describes when and how to inject a failure. A fault fires on the
+// request numbers in On, on every Every-th request, or otherwise with the
+// given Probability.
+type Fault struct {
+	Kind FaultKind
+	// Probability is the chance, between 0 and 1, of firing on each request
+	Probability float64
+	// On lists 1-based request numbers the fault fires on
+	On []int
+	// Every fires the fault on every n-th request
+	Every int
+	// Route limits the fault to requests for a route template, if set
+	Route string
+	// Latency is the delay added by FaultLatency
+	Latency time.Duration
+	// StatusCode is the status sent by FaultServerError (default 503)
+	StatusCode int
+	// RateLimitReset is how far in the future FaultRateLimit's reset time is.
+	// Zero sends a value the client cannot parse, so it retries after its
+	// default wait time.
+	RateLimitReset time.Duration
+}
+
+// FaultInjector is a middleware that injects failures, for testing how code
+// built on Client behaves when the API misbehaves
+type FaultInjector struct {
+	faults          []Fault
+	rateLimitHeader string
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	requests int
+	injected map[FaultKind]int
+}
+
+// NewFaultInjector creates a fault injector. seed makes probabilistic faults
+// reproducible.
+func NewFaultInjector(seed int64, faults ...Fault) *FaultInjector {
+	return &FaultInjector{
+		faults:          faults,
+		rateLimitHeader: DefaultRateLimitConfig().HeaderName,
+		rand:            rand.New(rand.NewSource(seed)),
+		injected:        make(map[FaultKind]int),
+	}
+}
+
+// SetRateLimitHeader sets the header FaultRateLimit sends the reset time in
+func (f *FaultInjector) SetRateLimitHeader(name string) {
+	f.rateLimitHeader = name
+}
+
+// Injected returns how many times a kind of fault has been injected
+func (f *FaultInjector) Injected(kind FaultKind) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.injected[kind]
+}
+
+// Middleware returns the middleware that injects the faults
+func (f *FaultInjector) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			for _, fault := range f.fire(req) {
+				switch fault.Kind {
+				case FaultLatency:
+					select {
+					case <-time.After(fault.Latency):
+					case <-req.Context().Done():
+						return nil, req.Context().Err()
+					}
+				case FaultConnectionReset:
+					return nil, fmt.Errorf("injected fault: %w", syscall.ECONNRESET)
+				case FaultRateLimit:
+					resp := syntheticResponse(req, http.StatusTooManyRequests, "rate limit exceeded")
+					reset := "unknown"
+					if fault.RateLimitReset > 0 {
+						reset = strconv.FormatInt(time.Now().Add(fault.RateLimitReset).Unix(), 10)
+					}
+					resp.Header.Set(f.rateLimitHeader, reset)
+					return resp, nil
+				case FaultServerError:
+					status := fault.StatusCode
+					if status == 0 {
+						status = http.StatusServiceUnavailable
+					}
+					return syntheticResponse(req, status, http.StatusText(status)), nil
+				case FaultTruncatedBody:
+					resp, err := next.RoundTrip(req)
+					if err != nil {
+						return nil, err
+					}
+					return truncateResponse(resp)
+				case FaultMalformedXML:
+					resp, err := next.RoundTrip(req)
+					if err != nil {
+						return nil, err
+					}
+					return malformResponse(resp)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// fire returns the faults that fire for a request, in configuration order
+func (f *FaultInjector) fire(req *http.Request) []Fault {
+	route := routeFor(req.Context(), req.URL.Path)
+	if info, ok := RequestInfoFromContext(req.Context()); ok {
+		route = info.Route
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	var fired []Fault
+	for _, fault := range f.faults {
+		if fault.Route != "" && fault.Route != route {
+			continue
+		}
+		if f.scheduled(fault) || (fault.Probability > 0 && f.rand.Float64() < fault.Probability) {
+			f.injected[fault.Kind]++
+			fired = append(fired, fault)
+		}
+	}
+	return fired
+}
+
+// scheduled reports whether a fault is scheduled for the current request.
+// f.mu must be held.
+func (f *FaultInjector) scheduled(fault Fault) bool {
+	if fault.Every > 0 && f.requests%fault.Every == 0 {
+		return true
+	}
+	for _, n := range fault.On {
+		if n == f.requests {
+			return true
+		}
+	}
+	return false
+}
+
+// syntheticResponse builds a plain text response without sending a request
+func syntheticResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain"}},
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// truncateResponse cuts a response body in half, ending the read in
+// io.ErrUnexpectedEOF
+func truncateResponse(resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = body[:len(body)/2]
+	return replaceBody(resp, io.MultiReader(bytes.NewReader(body), errReader{io.ErrUnexpectedEOF})), nil
+}
+
+// malformedMarkup is inserted into responses by FaultMalformedXML
+var malformedMarkup = []byte("<FAULT>&injected;</fault>")
+
+// malformResponse inserts malformedMarkup after the first tag of a response
+// body, or at its start if it has none
+func malformResponse(resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	at := bytes.IndexByte(body, '>') + 1
+	body = slices.Insert(body, at, malformedMarkup...)
+	return replaceBody(resp, bytes.NewReader(body)), nil
+}
+
+// replaceBody replaces the body of a response, whose length is then unknown
+func replaceBody(resp *http.Response, body io.Reader) *http.Response {
+	resp.Body = io.NopCloser(body)
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return resp
+}
+
+// errReader returns err from every read
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
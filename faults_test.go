@@ -0,0 +1,109 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newFaultTestClient(t *testing.T, injector *FaultInjector) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<USER_LIST_OUTPUT><USER_LIST></USER_LIST></USER_LIST_OUTPUT>`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeXML)
+	client.Use(injector.Middleware())
+	return client
+}
+
+func TestFaultInjectorScheduledFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		fault   Fault
+		wantErr string
+		is      error
+	}{
+		{name: "connection reset", fault: Fault{Kind: FaultConnectionReset, On: []int{1}}, is: syscall.ECONNRESET},
+		{name: "server error", fault: Fault{Kind: FaultServerError, On: []int{1}, StatusCode: 502}, wantErr: "status 502"},
+		{name: "truncated body", fault: Fault{Kind: FaultTruncatedBody, On: []int{1}}, is: io.ErrUnexpectedEOF},
+		{name: "malformed xml", fault: Fault{Kind: FaultMalformedXML, On: []int{1}}, wantErr: "invalid character entity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := NewFaultInjector(1, tt.fault)
+			client := newFaultTestClient(t, injector)
+
+			var result struct{}
+			err := client.Get(context.Background(), "/msp/user_list.php", nil, &result)
+			if err == nil {
+				t.Fatal("Get() error = nil, want an injected failure")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("Get() error = %v, want %v", err, tt.is)
+			}
+			if tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Get() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if got := injector.Injected(tt.fault.Kind); got != 1 {
+				t.Errorf("Injected() = %d, want 1", got)
+			}
+
+			// The schedule only covers the first request
+			if err := client.Get(context.Background(), "/msp/user_list.php", nil, &result); err != nil {
+				t.Errorf("second Get() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestFaultInjectorRateLimitIsRetried(t *testing.T) {
+	injector := NewFaultInjector(1, Fault{Kind: FaultRateLimit, On: []int{1}})
+	client := newFaultTestClient(t, injector)
+	client.SetRateLimitConfig(testRateLimitConfig())
+
+	var retries int
+	client.OnRetry(func(resp *http.Response, attempt int, wait time.Duration) {
+		retries++
+	})
+	var result struct{}
+	if err := client.Get(context.Background(), "/msp/user_list.php", nil, &result); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if retries != 1 {
+		t.Errorf("retries = %d, want 1 after the injected rate limit", retries)
+	}
+}
+
+func TestFaultInjectorProbabilityAndRoute(t *testing.T) {
+	injector := NewFaultInjector(42,
+		Fault{Kind: FaultLatency, Probability: 1, Latency: time.Millisecond},
+		Fault{Kind: FaultServerError, Probability: 0.5, Route: "/flaky"},
+	)
+	client := newFaultTestClient(t, injector)
+
+	var failures int
+	for i := 0; i < 20; i++ {
+		if err := client.Get(context.Background(), "/flaky", nil, nil); err != nil {
+			failures++
+		}
+		if err := client.Get(context.Background(), "/stable", nil, nil); err != nil {
+			t.Fatalf("Get() of an unaffected route error = %v", err)
+		}
+	}
+	if failures == 0 || failures == 20 {
+		t.Errorf("failures = %d, want some but not all of 20", failures)
+	}
+	if got := injector.Injected(FaultLatency); got != 40 {
+		t.Errorf("latency injected %d times, want 40", got)
+	}
+}
@@ -2,7 +2,10 @@ module github.com/ddfelts/godefaultapi
 
 go 1.24.1
 
-require github.com/schollz/progressbar/v3 v3.18.0
+require (
+	github.com/klauspost/compress v1.19.0
+	github.com/schollz/progressbar/v3 v3.18.0
+)
 
 require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
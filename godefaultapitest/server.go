@@ -0,0 +1,268 @@
+// Package godefaultapitest provides a programmable fake API server for
+// unit-testing code built on godefaultapi.Client.
+package godefaultapitest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ddfelts/godefaultapi"
+)
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// Response is a canned response served by a route
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Latency delays the response
+	Latency time.Duration
+}
+
+// Route is an expected method and path with its canned responses. Responses
+// are served in order and the last one repeats.
+type Route struct {
+	// mu is the server's lock, which guards responses
+	mu        *sync.Mutex
+	method    string
+	path      string
+	responses []Response
+}
+
+// Server is a fake API server
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	routes    map[string]*Route
+	requests  map[string][]Request
+	unmatched []Request
+}
+
+// NewServer starts a fake API server. It is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		routes:   make(map[string]*Route),
+		requests: make(map[string][]Request),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client for the server that responds in contentType and
+// retries rate limited requests without a noticeable delay
+func (s *Server) Client(contentType godefaultapi.ContentType) *godefaultapi.Client {
+	client := godefaultapi.NewClient(s.URL)
+	client.SetResponseType(contentType)
+	config := godefaultapi.DefaultRateLimitConfig()
+	config.DefaultWaitTime = time.Millisecond
+	client.SetRateLimitConfig(config)
+	return client
+}
+
+// Handle registers an expected route. The path excludes the query string.
+func (s *Server) Handle(method, path string) *Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	route := &Route{mu: &s.mu, method: method, path: path}
+	s.routes[routeKey(method, path)] = route
+	return route
+}
+
+// Respond adds a response with a raw body
+func (r *Route) Respond(statusCode int, contentType string, body []byte) *Route {
+	header := make(http.Header)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, Response{StatusCode: statusCode, Header: header, Body: body})
+	return r
+}
+
+// RespondJSON adds a response with v encoded as JSON
+func (r *Route) RespondJSON(statusCode int, v interface{}) *Route {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("godefaultapitest: encoding JSON response: %v", err))
+	}
+	return r.Respond(statusCode, string(godefaultapi.ContentTypeJSON), body)
+}
+
+// RespondXML adds a response with v encoded as XML. Strings and byte slices
+// are sent as is.
+func (r *Route) RespondXML(statusCode int, v interface{}) *Route {
+	var body []byte
+	switch x := v.(type) {
+	case string:
+		body = []byte(x)
+	case []byte:
+		body = x
+	default:
+		var err error
+		if body, err = xml.Marshal(v); err != nil {
+			panic(fmt.Sprintf("godefaultapitest: encoding XML response: %v", err))
+		}
+	}
+	return r.Respond(statusCode, string(godefaultapi.ContentTypeXML), body)
+}
+
+// RespondError adds an error response with a plain text body
+func (r *Route) RespondError(statusCode int, message string) *Route {
+	return r.Respond(statusCode, "text/plain", []byte(message))
+}
+
+// RespondRateLimited adds a 429 response carrying the rate limit reset
+// header, which makes the client wait until reset and retry. A zero reset
+// sends a value the client cannot parse, so it retries after its default
+// wait time instead.
+func (r *Route) RespondRateLimited(reset time.Time) *Route {
+	value := "unknown"
+	if !reset.IsZero() {
+		value = strconv.FormatInt(reset.Unix(), 10)
+	}
+	r.Respond(http.StatusTooManyRequests, "text/plain", []byte("rate limit exceeded"))
+	return r.WithHeader(godefaultapi.DefaultRateLimitConfig().HeaderName, value)
+}
+
+// WithHeader sets a header on the most recently added response
+func (r *Route) WithHeader(key, value string) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last().Header.Set(key, value)
+	return r
+}
+
+// WithLatency delays the most recently added response
+func (r *Route) WithLatency(latency time.Duration) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last().Latency = latency
+	return r
+}
+
+// last returns the most recently added response, adding an empty 200 if
+// none. r.mu must be held.
+func (r *Route) last() *Response {
+	if len(r.responses) == 0 {
+		r.responses = append(r.responses, Response{StatusCode: http.StatusOK, Header: make(http.Header)})
+	}
+	return &r.responses[len(r.responses)-1]
+}
+
+// Calls returns the number of requests received for a route
+func (s *Server) Calls(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests[routeKey(method, path)])
+}
+
+// Requests returns the requests received for a route, in order
+func (s *Server) Requests(method, path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests[routeKey(method, path)]...)
+}
+
+// Unmatched returns requests that did not match a registered route
+func (s *Server) Unmatched() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.unmatched...)
+}
+
+// AssertCalls fails the test if a route was not called exactly n times
+func (s *Server) AssertCalls(t testing.TB, method, path string, n int) {
+	t.Helper()
+	if got := s.Calls(method, path); got != n {
+		t.Errorf("%s %s called %d times, want %d", method, path, got, n)
+	}
+}
+
+// AssertBody fails the test if the i-th request to a route did not have body
+func (s *Server) AssertBody(t testing.TB, method, path string, i int, body string) {
+	t.Helper()
+	requests := s.Requests(method, path)
+	if i >= len(requests) {
+		t.Errorf("%s %s received %d requests, want at least %d", method, path, len(requests), i+1)
+		return
+	}
+	if got := string(requests[i].Body); got != body {
+		t.Errorf("%s %s request %d body = %q, want %q", method, path, i, got, body)
+	}
+}
+
+// AssertNoUnmatched fails the test if any request did not match a route
+func (s *Server) AssertNoUnmatched(t testing.TB) {
+	t.Helper()
+	for _, req := range s.Unmatched() {
+		t.Errorf("unexpected request %s %s", req.Method, req.Path)
+	}
+}
+
+// serveHTTP records the request and serves the next canned response
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	key := routeKey(r.Method, r.URL.Path)
+	s.mu.Lock()
+	route, ok := s.routes[key]
+	if !ok {
+		s.unmatched = append(s.unmatched, req)
+		s.mu.Unlock()
+		http.Error(w, fmt.Sprintf("no route registered for %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+	n := len(s.requests[key])
+	s.requests[key] = append(s.requests[key], req)
+	resp := Response{StatusCode: http.StatusOK}
+	if len(route.responses) > 0 {
+		resp = route.responses[min(n, len(route.responses)-1)]
+		resp.Header = resp.Header.Clone()
+	}
+	s.mu.Unlock()
+
+	if resp.Latency > 0 {
+		select {
+		case <-time.After(resp.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// routeKey identifies a route
+func routeKey(method, path string) string {
+	return method + " " + path
+}
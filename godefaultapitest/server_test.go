@@ -0,0 +1,85 @@
+package godefaultapitest
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ddfelts/godefaultapi"
+)
+
+type userList struct {
+	XMLName xml.Name `xml:"USER_LIST_OUTPUT"`
+	Users   []string `xml:"USER_LIST>USER>USER_LOGIN"`
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(t)
+	server.Handle(http.MethodGet, "/msp/user_list.php").
+		RespondRateLimited(time.Time{}).
+		RespondXML(http.StatusOK, `<USER_LIST_OUTPUT><USER_LIST><USER><USER_LOGIN>jdoe</USER_LOGIN></USER></USER_LIST></USER_LIST_OUTPUT>`)
+	server.Handle(http.MethodPost, "/msp/user.php").
+		RespondError(http.StatusInternalServerError, "internal error")
+
+	client := server.Client(godefaultapi.ContentTypeXML)
+
+	var users userList
+	if err := client.Get(context.Background(), "/msp/user_list.php", nil, &users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(users.Users) != 1 || users.Users[0] != "jdoe" {
+		t.Errorf("users = %+v", users)
+	}
+	server.AssertCalls(t, http.MethodGet, "/msp/user_list.php", 2)
+
+	err := client.Post(context.Background(), "/msp/user.php?action=add", []byte("email=a@b.c"), nil)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Post() error = %v, want a 500 error", err)
+	}
+	server.AssertBody(t, http.MethodPost, "/msp/user.php", 0, "email=a@b.c")
+	if got := server.Requests(http.MethodPost, "/msp/user.php")[0].Query; got != "action=add" {
+		t.Errorf("query = %q, want action=add", got)
+	}
+
+	server.AssertNoUnmatched(t)
+	if err := client.Get(context.Background(), "/unknown", nil, nil); err == nil {
+		t.Error("Get() of an unregistered route should fail")
+	}
+	if len(server.Unmatched()) != 1 {
+		t.Errorf("unmatched = %d, want 1", len(server.Unmatched()))
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	server := NewServer(t)
+	server.Handle(http.MethodGet, "/slow").RespondJSON(http.StatusOK, map[string]string{"ok": "yes"}).WithLatency(50 * time.Millisecond)
+	client := server.Client(godefaultapi.ContentTypeJSON)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.Get(ctx, "/slow", nil, nil); err == nil {
+		t.Error("Get() should time out")
+	}
+}
+
+func TestServerScriptingWhileServing(t *testing.T) {
+	server := NewServer(t)
+	route := server.Handle(http.MethodGet, "/status")
+	client := server.Client(godefaultapi.ContentTypeJSON)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			client.Get(context.Background(), "/status", nil, nil)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		route.RespondJSON(http.StatusOK, map[string]int{"n": i}).WithHeader("X-N", "1").WithLatency(0)
+	}
+	<-done
+	server.AssertCalls(t, http.MethodGet, "/status", 20)
+}
@@ -0,0 +1,85 @@
+package godefaultapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrRequestAborted is returned when a BeforeRequest hook aborts a request
+var ErrRequestAborted = errors.New("request aborted by hook")
+
+// BeforeRequestHook is called for every attempt once the request is built
+// and before it is authenticated and sent. Returning an error aborts the call.
+type BeforeRequestHook func(req *http.Request) error
+
+// AfterResponseHook is called for every attempt that receives a response,
+// before the body is read
+type AfterResponseHook func(resp *http.Response)
+
+// RetryHook is called before a rate limited attempt is retried, with the
+// wait chosen by the rate limiter
+type RetryHook func(resp *http.Response, attempt int, wait time.Duration)
+
+// ErrorHook is called when a call fails. req is the last attempted request,
+// or nil if none was built.
+type ErrorHook func(req *http.Request, err error)
+
+// hooks holds the registered hook lists
+type hooks struct {
+	beforeRequest []BeforeRequestHook
+	afterResponse []AfterResponseHook
+	onRetry       []RetryHook
+	onError       []ErrorHook
+}
+
+// OnBeforeRequest registers hooks run in order before every attempt
+func (c *Client) OnBeforeRequest(fns ...BeforeRequestHook) {
+	c.hooks.beforeRequest = append(c.hooks.beforeRequest, fns...)
+}
+
+// OnAfterResponse registers hooks run in order after every response
+func (c *Client) OnAfterResponse(fns ...AfterResponseHook) {
+	c.hooks.afterResponse = append(c.hooks.afterResponse, fns...)
+}
+
+// OnRetry registers hooks run in order before every retry
+func (c *Client) OnRetry(fns ...RetryHook) {
+	c.hooks.onRetry = append(c.hooks.onRetry, fns...)
+}
+
+// OnError registers hooks run in order when a call fails
+func (c *Client) OnError(fns ...ErrorHook) {
+	c.hooks.onError = append(c.hooks.onError, fns...)
+}
+
+// runBeforeRequest runs the BeforeRequest hooks, stopping at the first error
+func (h *hooks) runBeforeRequest(req *http.Request) error {
+	for _, fn := range h.beforeRequest {
+		if err := fn(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterResponse runs the AfterResponse hooks
+func (h *hooks) runAfterResponse(resp *http.Response) {
+	for _, fn := range h.afterResponse {
+		fn(resp)
+	}
+}
+
+// runOnRetry runs the OnRetry hooks
+func (h *hooks) runOnRetry(resp *http.Response, attempt int, wait time.Duration) {
+	for _, fn := range h.onRetry {
+		fn(resp, attempt, wait)
+	}
+}
+
+// runOnError runs the OnError hooks
+func (h *hooks) runOnError(req *http.Request, err error) {
+	for _, fn := range h.onError {
+		fn(req, err)
+	}
+}
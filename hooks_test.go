@@ -0,0 +1,99 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if got := r.Header.Get("X-Correlation-Id"); got != "corr-1" {
+			t.Errorf("attempt %d: X-Correlation-Id = %q", attempts, got)
+		}
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		if attempts == 1 {
+			w.Header().Set("X-RateLimit-Reset", "soon")
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetRateLimitConfig(testRateLimitConfig())
+
+	var order []string
+	var remaining string
+	var retryWait time.Duration
+	client.OnBeforeRequest(
+		func(req *http.Request) error {
+			order = append(order, "before-1")
+			req.Header.Set("X-Correlation-Id", "corr-1")
+			return nil
+		},
+		func(req *http.Request) error {
+			order = append(order, "before-2")
+			return nil
+		},
+	)
+	client.OnAfterResponse(func(resp *http.Response) {
+		order = append(order, "after")
+		remaining = resp.Header.Get("X-RateLimit-Remaining")
+	})
+	client.OnRetry(func(resp *http.Response, attempt int, wait time.Duration) {
+		order = append(order, "retry")
+		retryWait = wait
+	})
+	client.OnError(func(req *http.Request, err error) {
+		t.Errorf("OnError called for a successful call: %v", err)
+	})
+
+	var result map[string]interface{}
+	if err := client.Get(context.Background(), "/", nil, &result); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	want := "before-1,before-2,after,retry,before-1,before-2,after"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("hook order = %s, want %s", got, want)
+	}
+	if remaining != "42" {
+		t.Errorf("remaining = %q, want 42", remaining)
+	}
+	if retryWait != time.Millisecond {
+		t.Errorf("retry wait = %v, want the default wait of 1ms", retryWait)
+	}
+}
+
+func TestBeforeRequestHookAborts(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	denied := errors.New("dry run")
+	client.OnBeforeRequest(func(req *http.Request) error { return denied })
+
+	var hookErr error
+	client.OnError(func(req *http.Request, err error) { hookErr = err })
+
+	err := client.Post(context.Background(), "/msp/user.php", []byte("a=1"), nil)
+	if !errors.Is(err, ErrRequestAborted) || !errors.Is(err, denied) {
+		t.Errorf("Post() error = %v, want ErrRequestAborted wrapping the hook error", err)
+	}
+	if hookErr != err {
+		t.Errorf("OnError received %v, want %v", hookErr, err)
+	}
+	if hits != 0 {
+		t.Errorf("hits = %d, want 0", hits)
+	}
+}
@@ -0,0 +1,181 @@
+package godefaultapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyHeader is the header idempotency keys are sent in by default
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// ErrIdempotencyKeyInFlight is returned when a call uses the idempotency key
+// of another call that has not finished
+var ErrIdempotencyKeyInFlight = errors.New("idempotency key is already in flight")
+
+// ErrIdempotencyKeyUnresolved is returned when a call reuses the idempotency
+// key of an earlier call that may have reached the server, and the call is
+// not marked safe to re-send with WithRetrySafe
+var ErrIdempotencyKeyUnresolved = errors.New("an earlier call with this idempotency key may have succeeded")
+
+// IdempotencyConfig configures idempotency keys
+type IdempotencyConfig struct {
+	// Header is the request header the key is sent in
+	Header string
+	// Generate adds a random key to POST and PATCH calls that have none
+	Generate bool
+	// RetryKeyed treats calls that carry a key as safe to re-send, for
+	// servers that deduplicate requests by key
+	RetryKeyed bool
+	// Remember is how long the key of a call with an unknown outcome is
+	// remembered. Zero forgets it at once.
+	Remember time.Duration
+}
+
+// DefaultIdempotencyConfig returns a default idempotency configuration that
+// generates keys for POST and PATCH calls
+func DefaultIdempotencyConfig() *IdempotencyConfig {
+	return &IdempotencyConfig{
+		Header:   DefaultIdempotencyHeader,
+		Generate: true,
+		Remember: time.Hour,
+	}
+}
+
+// SetIdempotency configures idempotency keys. Keys given with
+// WithIdempotencyKey are sent in DefaultIdempotencyHeader without it. Passing
+// nil restores that default.
+func (c *Client) SetIdempotency(config *IdempotencyConfig) {
+	c.idempotency = config
+}
+
+// idempotencyKeyKey is the context key for a caller provided idempotency key
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a context whose call sends key as its
+// idempotency key. The client remembers keys in use: a second call with the
+// same key fails with ErrIdempotencyKeyInFlight while the first is running,
+// and with ErrIdempotencyKeyUnresolved after the first failed in a way that
+// may have reached the server, unless it is marked with WithRetrySafe.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// retrySafeKey is the context key for a call's retry-safe classification
+type retrySafeKey struct{}
+
+// WithRetrySafe returns a context whose call is, or is not, safe to send
+// again after an attempt that may have reached the server. By default GET,
+// HEAD, OPTIONS, TRACE, PUT and DELETE calls are safe and POST and PATCH
+// calls are not. Unsafe calls still fail over to another base URL when the
+// attempt cannot have been processed, e.g. when the connection was refused.
+func WithRetrySafe(ctx context.Context, safe bool) context.Context {
+	return context.WithValue(ctx, retrySafeKey{}, safe)
+}
+
+// NewIdempotencyKey returns a random version 4 UUID for use as an
+// idempotency key
+func NewIdempotencyKey() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// idempotencyHeader returns the header idempotency keys are sent in
+func (c *Client) idempotencyHeader() string {
+	if c.idempotency == nil || c.idempotency.Header == "" {
+		return DefaultIdempotencyHeader
+	}
+	return c.idempotency.Header
+}
+
+// idempotencyKey returns the idempotency key of a call and whether the
+// caller provided it
+func (c *Client) idempotencyKey(ctx context.Context, method string) (string, bool) {
+	if key, ok := ctx.Value(idempotencyKeyKey{}).(string); ok && key != "" {
+		return key, true
+	}
+	if c.idempotency != nil && c.idempotency.Generate && (method == http.MethodPost || method == http.MethodPatch) {
+		return NewIdempotencyKey(), false
+	}
+	return "", false
+}
+
+// retrySafe reports whether a call may be sent again after an attempt that
+// may have reached the server
+func (c *Client) retrySafe(ctx context.Context, method, key string) bool {
+	if safe, ok := ctx.Value(retrySafeKey{}).(bool); ok {
+		return safe
+	}
+	if key != "" && c.idempotency != nil && c.idempotency.RetryKeyed {
+		return true
+	}
+	return idempotentMethod(method)
+}
+
+// idempotencyKeys records the caller provided idempotency keys in flight and
+// those of calls with an unknown outcome
+type idempotencyKeys struct {
+	mu         sync.Mutex
+	inFlight   map[string]bool
+	unresolved map[string]time.Time
+	now        func() time.Time
+}
+
+// newIdempotencyKeys creates an empty record of idempotency keys
+func newIdempotencyKeys() *idempotencyKeys {
+	return &idempotencyKeys{
+		inFlight:   make(map[string]bool),
+		unresolved: make(map[string]time.Time),
+		now:        time.Now,
+	}
+}
+
+// begin records that a call with key has started
+func (k *idempotencyKeys) begin(key string, retrySafe bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.now()
+	for unresolved, expires := range k.unresolved {
+		if !expires.After(now) {
+			delete(k.unresolved, unresolved)
+		}
+	}
+
+	if k.inFlight[key] {
+		return fmt.Errorf("%w: %s", ErrIdempotencyKeyInFlight, key)
+	}
+	if _, ok := k.unresolved[key]; ok && !retrySafe {
+		return fmt.Errorf("%w: %s", ErrIdempotencyKeyUnresolved, key)
+	}
+	k.inFlight[key] = true
+	return nil
+}
+
+// end records that a call with key has finished. The key is remembered for
+// remember if the call's outcome is unknown.
+func (k *idempotencyKeys) end(key string, unresolved bool, remember time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.inFlight, key)
+	if unresolved && remember > 0 {
+		k.unresolved[key] = k.now().Add(remember)
+	} else {
+		delete(k.unresolved, key)
+	}
+}
+
+// rememberUnresolved returns how long keys of calls with an unknown outcome
+// are remembered
+func (c *Client) rememberUnresolved() time.Duration {
+	if c.idempotency == nil {
+		return DefaultIdempotencyConfig().Remember
+	}
+	return c.idempotency.Remember
+}
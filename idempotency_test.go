@@ -0,0 +1,176 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// keyServer responds with status and records the idempotency keys it receives
+func keyServer(t *testing.T, status int, header string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(header))
+		w.WriteHeader(status)
+		w.Write([]byte("<OK/>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &keys
+}
+
+func TestIdempotencyKeyGenerated(t *testing.T) {
+	primary, primaryKeys := keyServer(t, http.StatusServiceUnavailable, "X-Idempotency-Key")
+	secondary, secondaryKeys := keyServer(t, http.StatusOK, "X-Idempotency-Key")
+
+	client := NewClient(primary.URL)
+	client.SetBaseURLs(primary.URL, secondary.URL)
+	config := DefaultIdempotencyConfig()
+	config.Header = "X-Idempotency-Key"
+	client.SetIdempotency(config)
+
+	if err := client.Post(context.Background(), "/users", []byte("<user/>"), nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if len(*primaryKeys) != 1 || len(*secondaryKeys) != 1 {
+		t.Fatalf("keys = %q, %q, want one attempt each", *primaryKeys, *secondaryKeys)
+	}
+	if key := (*primaryKeys)[0]; len(key) != 36 || key != (*secondaryKeys)[0] {
+		t.Errorf("keys = %q, %q, want the same generated key", key, (*secondaryKeys)[0])
+	}
+
+	if err := client.Get(context.Background(), "/users", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if key := (*secondaryKeys)[1]; key != "" {
+		t.Errorf("GET key = %q, want none", key)
+	}
+}
+
+func TestRetrySafeClassification(t *testing.T) {
+	safe, unsafe := true, false
+	tests := []struct {
+		name       string
+		method     string
+		safe       *bool
+		wantResent bool
+	}{
+		{"GET", http.MethodGet, nil, true},
+		{"PUT", http.MethodPut, nil, true},
+		{"POST", http.MethodPost, nil, false},
+		{"POST marked safe", http.MethodPost, &safe, true},
+		{"GET marked unsafe", http.MethodGet, &unsafe, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status atomic.Int32
+			status.Store(http.StatusGatewayTimeout)
+			first, _ := countingServer(t, &status)
+			second, secondCalls := countingServer(t, &status)
+
+			client := NewClient(first.URL)
+			client.SetBaseURLs(first.URL, second.URL)
+			ctx := context.Background()
+			if tt.safe != nil {
+				ctx = WithRetrySafe(ctx, *tt.safe)
+			}
+			client.Do(ctx, tt.method, "/", nil, nil)
+			if resent := secondCalls.Load() == 1; resent != tt.wantResent {
+				t.Errorf("re-sent after 504 = %v, want %v", resent, tt.wantResent)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyUnresolved(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusGatewayTimeout)
+	server, calls := countingServer(t, &status)
+	client := NewClient(server.URL)
+	ctx := WithIdempotencyKey(context.Background(), "add-user-42")
+
+	if err := client.Post(ctx, "/users", nil, nil); err == nil {
+		t.Fatal("Post() error = nil, want status 504")
+	}
+
+	// The first call may have created the user, so the key blocks a retry
+	err := client.Post(ctx, "/users", nil, nil)
+	if !errors.Is(err, ErrIdempotencyKeyUnresolved) {
+		t.Fatalf("Post() error = %v, want ErrIdempotencyKeyUnresolved", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+
+	// unless the caller allows it, which resolves the key
+	status.Store(http.StatusOK)
+	if err := client.Post(WithRetrySafe(ctx, true), "/users", nil, nil); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if err := client.Post(ctx, "/users", nil, nil); err != nil {
+		t.Fatalf("Post() after resolution error = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("<OK/>"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx := WithIdempotencyKey(context.Background(), "scan-1")
+	done := make(chan error)
+	go func() { done <- client.Post(ctx, "/scans", nil, nil) }()
+
+	<-started
+	if err := client.Post(ctx, "/scans", nil, nil); !errors.Is(err, ErrIdempotencyKeyInFlight) {
+		t.Errorf("concurrent Post() error = %v, want ErrIdempotencyKeyInFlight", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+}
+
+func TestUnsafeCallRateLimitRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{"created with reset header", http.StatusCreated, 1},
+		{"bad request with reset header", http.StatusBadRequest, 1},
+		{"too many requests", http.StatusTooManyRequests, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.Header().Set("X-RateLimit-Reset", "soon")
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.SetRateLimitConfig(testRateLimitConfig())
+			client.Post(context.Background(), "/users", []byte("<user/>"), nil)
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package godefaultapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultLogBodyLimit is the default number of body bytes logged at debug level
+const defaultLogBodyLimit = 4096
+
+// redactedValue replaces sensitive values in logs
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are headers whose values are never logged
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Signature":         true,
+}
+
+// sensitiveQueryParams are query parameters whose values are never logged
+var sensitiveQueryParams = map[string]bool{
+	"password":      true,
+	"passwd":        true,
+	"pwd":           true,
+	"token":         true,
+	"access_token":  true,
+	"secret":        true,
+	"client_secret": true,
+	"api_key":       true,
+	"apikey":        true,
+}
+
+// CredentialReporter is implemented by authenticators that send credentials in
+// headers or query parameters outside the built-in sensitive lists, so their
+// values are redacted from logs, recordings, dry-run captures and audit records
+type CredentialReporter interface {
+	CredentialNames() (headers, queryParams []string)
+}
+
+// credentialNamesKey carries the credential names of the client authenticator
+type credentialNamesKey struct{}
+
+// credentialNames are the canonical header and lower case query parameter
+// names an authenticator sends credentials in, and its cache scope if any
+type credentialNames struct {
+	headers map[string]bool
+	params  map[string]bool
+	scope   string
+}
+
+// withCredentialNames returns ctx carrying the credential names reported by
+// the client authenticator
+func (c *Client) withCredentialNames(ctx context.Context) context.Context {
+	names := credentialNames{headers: map[string]bool{}, params: map[string]bool{}}
+	if reporter, ok := c.authenticator.(CredentialReporter); ok {
+		headers, params := reporter.CredentialNames()
+		for _, name := range headers {
+			names.headers[http.CanonicalHeaderKey(name)] = true
+		}
+		for _, name := range params {
+			names.params[strings.ToLower(name)] = true
+		}
+	}
+	if scoper, ok := c.authenticator.(cacheScoper); ok {
+		names.scope = scoper.cacheScope()
+	}
+	return context.WithValue(ctx, credentialNamesKey{}, names)
+}
+
+// sensitiveHeader reports whether the value of header key is never logged
+func sensitiveHeader(ctx context.Context, key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	names, _ := ctx.Value(credentialNamesKey{}).(credentialNames)
+	return sensitiveHeaders[key] || names.headers[key]
+}
+
+// sensitiveQueryParam reports whether the value of parameter key is never logged
+func sensitiveQueryParam(ctx context.Context, key string) bool {
+	key = strings.ToLower(key)
+	names, _ := ctx.Value(credentialNamesKey{}).(credentialNames)
+	return sensitiveQueryParams[key] || names.params[key]
+}
+
+// SetLogger enables structured request logging. Every call is logged with its
+// method, URL, status, duration and attempts; headers and bodies are added at
+// debug level. Credentials are redacted. A nil logger disables logging.
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+// SetLogBodyLimit sets the maximum number of body bytes logged at debug level.
+// A negative limit logs bodies in full.
+func (c *Client) SetLogBodyLimit(limit int) {
+	c.logBodyLimit = limit
+}
+
+// logRequest logs a completed call
+func (c *Client) logRequest(ctx context.Context, req *http.Request, method, path string, reqBody []byte, status int, respBody []byte, attempts int, duration time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+
+	rawURL := c.baseURL + path
+	if req != nil {
+		rawURL = req.URL.String()
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("url", redactURL(ctx, rawURL)),
+		slog.Int("status", status),
+		slog.Duration("duration", duration),
+		slog.Int("attempts", attempts),
+	}
+
+	if c.logger.Enabled(ctx, slog.LevelDebug) {
+		if req != nil {
+			attrs = append(attrs, slog.Any("request_headers", redactHeaders(ctx, req.Header)))
+			if stats, ok := connStatsFromContext(req.Context()); ok {
+				attrs = append(attrs, slog.Group("conn",
+					slog.Bool("reused", stats.Reused),
+					slog.String("protocol", stats.Protocol),
+					slog.Duration("dns", stats.DNS),
+					slog.Duration("connect", stats.Connect),
+					slog.Duration("tls", stats.TLS),
+					slog.Duration("ttfb", stats.TTFB),
+				))
+			}
+		}
+		if len(reqBody) > 0 {
+			attrs = append(attrs, slog.String("request_body", c.truncateBody(redactFormBody(req, reqBody))))
+		}
+		if len(respBody) > 0 {
+			attrs = append(attrs, slog.String("response_body", c.truncateBody(respBody)))
+		}
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactError(ctx, err, rawURL)))
+		c.logger.LogAttrs(ctx, slog.LevelError, "request failed", attrs...)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "request completed", attrs...)
+}
+
+// logRetry logs a rate limited attempt that is about to be retried
+func (c *Client) logRetry(ctx context.Context, req *http.Request, attempt int, wait time.Duration) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "request rate limited, retrying",
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(ctx, req.URL.Redacted())),
+		slog.Int("attempt", attempt),
+		slog.Duration("wait", wait),
+	)
+}
+
+// logFailover logs an attempt that failed against base and is repeated
+// against the next base URL
+func (c *Client) logFailover(ctx context.Context, method, base, path string, attempt int, err error, resp *http.Response) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("url", redactURL(ctx, base+path)),
+		slog.Int("attempt", attempt),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactError(ctx, err, base+path)))
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelWarn, "base URL failed, failing over", attrs...)
+}
+
+// logDryRun logs a request captured in dry-run mode
+func (c *Client) logDryRun(ctx context.Context, captured CapturedRequest) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", captured.Method),
+		slog.String("url", captured.URL),
+	}
+	if len(captured.Body) > 0 {
+		attrs = append(attrs, slog.String("request_body", c.truncateBody(captured.Body)))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "dry run, request not sent", attrs...)
+}
+
+// truncateBody returns body as a string cut to the configured limit
+func (c *Client) truncateBody(body []byte) string {
+	if c.logBodyLimit < 0 || len(body) <= c.logBodyLimit {
+		return string(body)
+	}
+	return string(body[:c.logBodyLimit]) + "...(truncated)"
+}
+
+// redactURL returns rawURL with the values of sensitive query parameters and
+// any user info replaced
+func redactURL(ctx context.Context, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if u.User != nil {
+		u.User = url.User(redactedValue)
+	}
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	u.RawQuery = redactValues(ctx, u.Query()).Encode()
+	return u.String()
+}
+
+// redactValues replaces the values of sensitive parameters in place and
+// returns values
+func redactValues(ctx context.Context, values url.Values) url.Values {
+	for key, vals := range values {
+		if sensitiveQueryParam(ctx, key) {
+			for i := range vals {
+				vals[i] = redactedValue
+			}
+		}
+	}
+	return values
+}
+
+// redactFormBody returns body with the values of sensitive parameters
+// replaced if req sent it as a URL encoded form
+func redactFormBody(req *http.Request, body []byte) []byte {
+	if req == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return body
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+	return []byte(redactValues(req.Context(), values).Encode())
+}
+
+// redactHeaders returns a copy of h with sensitive header values replaced
+func redactHeaders(ctx context.Context, h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for key, values := range h {
+		if sensitiveHeader(ctx, key) {
+			redacted[key] = []string{redactedValue}
+			continue
+		}
+		redacted[key] = append([]string(nil), values...)
+	}
+	return redacted
+}
+
+// redactError returns the error message with the raw request URL replaced by
+// its redacted form, since transport errors embed the full URL
+func redactError(ctx context.Context, err error, rawURL string) string {
+	return strings.ReplaceAll(err.Error(), rawURL, redactURL(ctx, rawURL))
+}
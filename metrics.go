@@ -0,0 +1,256 @@
+package godefaultapi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the default request duration histogram buckets in seconds
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// defaultMaxRoutes bounds the number of distinct routes tracked
+const defaultMaxRoutes = 200
+
+// overflowRoute is reported for routes beyond the route limit
+const overflowRoute = "other"
+
+// Metrics records per-endpoint request counts, latencies, errors, retries and
+// rate limit waits, and exposes them in the Prometheus text format
+type Metrics struct {
+	mu        sync.Mutex
+	buckets   []float64
+	maxRoutes int
+	routes    map[string]bool
+	requests  map[requestKey]uint64
+	endpoints map[endpointKey]*endpointMetrics
+}
+
+// endpointKey identifies an endpoint by method and route template
+type endpointKey struct {
+	method string
+	route  string
+}
+
+// requestKey identifies a request counter series
+type requestKey struct {
+	endpointKey
+	code string
+}
+
+// endpointMetrics holds the per-endpoint series other than request counts
+type endpointMetrics struct {
+	errors        uint64
+	retries       uint64
+	rateLimitWait float64
+	bucketCounts  []uint64
+	durationSum   float64
+	durationCount uint64
+}
+
+// NewMetrics creates a metrics collector with the default latency buckets
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewMetricsWithBuckets creates a metrics collector with custom latency buckets in seconds
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Metrics{
+		buckets:   sorted,
+		maxRoutes: defaultMaxRoutes,
+		routes:    make(map[string]bool),
+		requests:  make(map[requestKey]uint64),
+		endpoints: make(map[endpointKey]*endpointMetrics),
+	}
+}
+
+// SetMaxRoutes sets the number of distinct routes tracked. Further routes are
+// recorded under the route "other".
+func (m *Metrics) SetMaxRoutes(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxRoutes = n
+}
+
+// Middleware returns the middleware that records metrics for every attempt
+func (m *Metrics) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			info, ok := RequestInfoFromContext(req.Context())
+			if !ok {
+				info = RequestInfo{Method: req.Method, Route: routeFor(req.Context(), req.URL.Path), Attempt: 1}
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			code := "error"
+			if err == nil {
+				code = strconv.Itoa(resp.StatusCode)
+			}
+			m.record(info, code, err != nil || resp.StatusCode >= 400, time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// record adds a single attempt to the collected metrics
+func (m *Metrics) record(info RequestInfo, code string, failed bool, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	route := info.Route
+	if !m.routes[route] {
+		if len(m.routes) >= m.maxRoutes {
+			route = overflowRoute
+		} else {
+			m.routes[route] = true
+		}
+	}
+
+	key := endpointKey{method: info.Method, route: route}
+	m.requests[requestKey{endpointKey: key, code: code}]++
+
+	e := m.endpoints[key]
+	if e == nil {
+		e = &endpointMetrics{bucketCounts: make([]uint64, len(m.buckets))}
+		m.endpoints[key] = e
+	}
+	if failed {
+		e.errors++
+	}
+	if info.Attempt > 1 {
+		e.retries++
+	}
+	e.rateLimitWait += info.RateLimitWait.Seconds()
+
+	seconds := duration.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			e.bucketCounts[i]++
+		}
+	}
+	e.durationSum += seconds
+	e.durationCount++
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	requestKeys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		if requestKeys[i].endpointKey != requestKeys[j].endpointKey {
+			return requestKeys[i].endpointKey.less(requestKeys[j].endpointKey)
+		}
+		return requestKeys[i].code < requestKeys[j].code
+	})
+
+	endpointKeys := make([]endpointKey, 0, len(m.endpoints))
+	for key := range m.endpoints {
+		endpointKeys = append(endpointKeys, key)
+	}
+	sort.Slice(endpointKeys, func(i, j int) bool {
+		return endpointKeys[i].less(endpointKeys[j])
+	})
+
+	cw.printf("# HELP godefaultapi_requests_total Total HTTP requests sent, including retries.\n")
+	cw.printf("# TYPE godefaultapi_requests_total counter\n")
+	for _, key := range requestKeys {
+		cw.printf("godefaultapi_requests_total{%s,code=\"%s\"} %d\n", key.labels(), escapeLabel(key.code), m.requests[key])
+	}
+
+	cw.printf("# HELP godefaultapi_request_errors_total Total requests that failed or returned a status of 400 or above.\n")
+	cw.printf("# TYPE godefaultapi_request_errors_total counter\n")
+	for _, key := range endpointKeys {
+		cw.printf("godefaultapi_request_errors_total{%s} %d\n", key.labels(), m.endpoints[key].errors)
+	}
+
+	cw.printf("# HELP godefaultapi_retries_total Total retried request attempts.\n")
+	cw.printf("# TYPE godefaultapi_retries_total counter\n")
+	for _, key := range endpointKeys {
+		cw.printf("godefaultapi_retries_total{%s} %d\n", key.labels(), m.endpoints[key].retries)
+	}
+
+	cw.printf("# HELP godefaultapi_rate_limit_wait_seconds_total Total time spent waiting on rate limits.\n")
+	cw.printf("# TYPE godefaultapi_rate_limit_wait_seconds_total counter\n")
+	for _, key := range endpointKeys {
+		cw.printf("godefaultapi_rate_limit_wait_seconds_total{%s} %s\n", key.labels(), formatFloat(m.endpoints[key].rateLimitWait))
+	}
+
+	cw.printf("# HELP godefaultapi_request_duration_seconds Request latency.\n")
+	cw.printf("# TYPE godefaultapi_request_duration_seconds histogram\n")
+	for _, key := range endpointKeys {
+		e := m.endpoints[key]
+		for i, bound := range m.buckets {
+			cw.printf("godefaultapi_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", key.labels(), formatFloat(bound), e.bucketCounts[i])
+		}
+		cw.printf("godefaultapi_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), e.durationCount)
+		cw.printf("godefaultapi_request_duration_seconds_sum{%s} %s\n", key.labels(), formatFloat(e.durationSum))
+		cw.printf("godefaultapi_request_duration_seconds_count{%s} %d\n", key.labels(), e.durationCount)
+	}
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// less orders endpoint keys by route, then method
+func (k endpointKey) less(other endpointKey) bool {
+	if k.route != other.route {
+		return k.route < other.route
+	}
+	return k.method < other.method
+}
+
+// labels formats the endpoint as Prometheus labels
+func (k endpointKey) labels() string {
+	return fmt.Sprintf("method=\"%s\",route=\"%s\"", escapeLabel(k.method), escapeLabel(k.route))
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a float as Prometheus expects
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter tracks bytes written and the first write error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// printf writes formatted output unless an earlier write failed
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
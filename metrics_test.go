@@ -0,0 +1,83 @@
+package godefaultapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case calls == 1:
+			w.Header().Set("X-RateLimit-Reset", "soon")
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	metrics := NewMetricsWithBuckets([]float64{1})
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetRateLimitConfig(&RateLimitConfig{
+		HeaderName:      "X-RateLimit-Reset",
+		MaxRetries:      3,
+		DefaultWaitTime: 10 * time.Millisecond,
+	})
+	client.Use(metrics.Middleware())
+
+	var result map[string]interface{}
+	if err := client.Get(context.Background(), "/api/2.0/fo/scan/?action=list&id=1", nil, &result); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := client.Get(context.Background(), "/missing", nil, &result); err == nil {
+		t.Fatal("Get() of missing route should fail")
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`godefaultapi_requests_total{method="GET",route="/api/2.0/fo/scan/",code="200"} 2`,
+		`godefaultapi_requests_total{method="GET",route="/missing",code="404"} 1`,
+		`godefaultapi_request_errors_total{method="GET",route="/missing"} 1`,
+		`godefaultapi_retries_total{method="GET",route="/api/2.0/fo/scan/"} 1`,
+		`godefaultapi_rate_limit_wait_seconds_total{method="GET",route="/api/2.0/fo/scan/"} 0.01`,
+		`godefaultapi_request_duration_seconds_bucket{method="GET",route="/api/2.0/fo/scan/",le="1"} 2`,
+		`godefaultapi_request_duration_seconds_count{method="GET",route="/missing"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "action=list") {
+		t.Errorf("metrics output contains query string:\n%s", out)
+	}
+}
+
+func TestMetricsMaxRoutes(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.SetMaxRoutes(1)
+	metrics.record(RequestInfo{Method: "GET", Route: "/a", Attempt: 1}, "200", false, time.Millisecond)
+	metrics.record(RequestInfo{Method: "GET", Route: "/b", Attempt: 1}, "200", false, time.Millisecond)
+
+	var sb strings.Builder
+	if _, err := metrics.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if !strings.Contains(sb.String(), `route="other"`) || strings.Contains(sb.String(), `route="/b"`) {
+		t.Errorf("routes beyond the limit should be reported as other:\n%s", sb.String())
+	}
+}
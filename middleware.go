@@ -0,0 +1,81 @@
+package godefaultapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps the transport used for every request attempt
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to the http.RoundTripper interface
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// RequestInfo describes the call an outgoing request attempt belongs to
+type RequestInfo struct {
+	// Method is the HTTP method of the call
+	Method string
+	// Route is the route template of the call, see WithRoute
+	Route string
+	// Attempt is the 1-based attempt number
+	Attempt int
+	// RateLimitWait is the rate limit wait that preceded this attempt
+	RateLimitWait time.Duration
+}
+
+// requestInfoKey is the context key for RequestInfo
+type requestInfoKey struct{}
+
+// RequestInfoFromContext returns the RequestInfo of a request attempt. It is
+// available from req.Context() inside middleware.
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+// Use adds middleware to the client. The first middleware added is the
+// outermost and sees each request first.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// send performs a single request attempt through the middleware chain. The
+// cache, if any, is outermost so that cache hits never reach the network.
+// Decompression is innermost so that middleware sees decoded bodies, followed
+// only by dry-run capture.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	httpClient := *c.httpClient
+	if isDownload(req.Context()) {
+		// Downloads can outlast the client timeout; their context bounds them
+		httpClient.Timeout = 0
+	}
+	if len(c.middlewares) == 0 && c.cache == nil && c.compression == nil && c.dryRun == nil {
+		return httpClient.Do(req)
+	}
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if c.httpClient.Transport != nil {
+		transport = c.httpClient.Transport
+	}
+	if c.dryRun != nil {
+		transport = c.dryRunTransport(transport)
+	}
+	if c.compression != nil {
+		transport = c.decompressor(transport)
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		transport = c.middlewares[i](transport)
+	}
+	if c.cache != nil {
+		transport = c.cache.middleware(c.rateLimit().HeaderName)(transport)
+	}
+
+	httpClient.Transport = transport
+	return httpClient.Do(req)
+}
@@ -0,0 +1,114 @@
+package godefaultapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// PathParams holds the values of the {name} placeholders in a path template
+type PathParams map[string]string
+
+// pathParamsKey is the context key for PathParams
+type pathParamsKey struct{}
+
+// WithPathParams returns a context whose calls fill the {name} placeholders
+// in their path from params, e.g. "/qps/rest/2.0/get/am/hostasset/{id}" or
+// "/api/2.0/fo/scan/?action=fetch&scan_ref={ref}". The template becomes the
+// call's route unless one is set with WithRoute.
+func WithPathParams(ctx context.Context, params PathParams) context.Context {
+	return context.WithValue(ctx, pathParamsKey{}, params)
+}
+
+// ExpandPath fills the {name} placeholders in template with params. Values
+// before the query string are escaped as a single path segment, so a value
+// such as "scan/1234.5678" cannot add segments, and values in the query
+// string are escaped as query values. Every placeholder must be bound and
+// every parameter used. Path values may not be empty, "." or "..".
+func ExpandPath(template string, params PathParams) (string, error) {
+	var b strings.Builder
+	used := make(map[string]bool)
+	inQuery := false
+	for i := 0; i < len(template); i++ {
+		ch := template[i]
+		if ch == '?' {
+			inQuery = true
+		}
+		name, ok := placeholderAt(template, i)
+		if !ok {
+			b.WriteByte(ch)
+			continue
+		}
+
+		value, bound := params[name]
+		if !bound {
+			return "", fmt.Errorf("missing path parameter %q", name)
+		}
+		used[name] = true
+		if inQuery {
+			b.WriteString(url.QueryEscape(value))
+		} else {
+			if value == "" || value == "." || value == ".." {
+				return "", fmt.Errorf("invalid value %q for path parameter %q", value, name)
+			}
+			b.WriteString(url.PathEscape(value))
+		}
+		i += len(name) + 1
+	}
+
+	var unused []string
+	for name := range params {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", fmt.Errorf("path parameters %q have no placeholder in %q", unused, template)
+	}
+	return b.String(), nil
+}
+
+// placeholderAt returns the name of the {name} placeholder starting at i.
+// Names are letters, digits and underscores, so other uses of braces, such
+// as JSON in a query string, are left alone.
+func placeholderAt(template string, i int) (string, bool) {
+	if template[i] != '{' {
+		return "", false
+	}
+	end := strings.IndexByte(template[i:], '}')
+	if end < 2 {
+		return "", false
+	}
+	name := template[i+1 : i+end]
+	for j := 0; j < len(name); j++ {
+		ch := name[j]
+		if ch != '_' && !('a' <= ch && ch <= 'z') && !('A' <= ch && ch <= 'Z') && !('0' <= ch && ch <= '9') {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// resolvePath expands the placeholders in path with the context's
+// PathParams and sets the template as the route. Paths of calls without
+// PathParams are used as they are, braces included.
+func resolvePath(ctx context.Context, path string) (context.Context, string, error) {
+	params, _ := ctx.Value(pathParamsKey{}).(PathParams)
+	if params == nil {
+		return ctx, path, nil
+	}
+
+	expanded, err := ExpandPath(path, params)
+	if err != nil {
+		return ctx, "", fmt.Errorf("error expanding path: %w", err)
+	}
+	if expanded != path {
+		if route, _ := ctx.Value(routeKey{}).(string); route == "" {
+			ctx = WithRoute(ctx, routeFor(context.Background(), path))
+		}
+	}
+	return ctx, expanded, nil
+}
@@ -0,0 +1,152 @@
+package godefaultapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   PathParams
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "path segment",
+			template: "/qps/rest/2.0/get/am/hostasset/{id}",
+			params:   PathParams{"id": "12345"},
+			want:     "/qps/rest/2.0/get/am/hostasset/12345",
+		},
+		{
+			name:     "slash escaped in segment",
+			template: "/scans/{ref}/hosts",
+			params:   PathParams{"ref": "scan/1234.5678"},
+			want:     "/scans/scan%2F1234.5678/hosts",
+		},
+		{
+			name:     "query value",
+			template: "/api/2.0/fo/scan/?action=fetch&scan_ref={ref}&output_format=json",
+			params:   PathParams{"ref": "scan/1234.5678&echo=1"},
+			want:     "/api/2.0/fo/scan/?action=fetch&scan_ref=scan%2F1234.5678%26echo%3D1&output_format=json",
+		},
+		{
+			name:     "repeated placeholder",
+			template: "/a/{x}/b/{x}",
+			params:   PathParams{"x": "1"},
+			want:     "/a/1/b/1",
+		},
+		{
+			name:     "braces that are not placeholders",
+			template: `/search?filter={"name":"web"}&x={}`,
+			want:     `/search?filter={"name":"web"}&x={}`,
+		},
+		{
+			name:     "empty query value",
+			template: "/list?tag={tag}",
+			params:   PathParams{"tag": ""},
+			want:     "/list?tag=",
+		},
+		{
+			name:     "missing parameter",
+			template: "/assets/{id}/tags/{tag}",
+			params:   PathParams{"id": "1"},
+			wantErr:  `missing path parameter "tag"`,
+		},
+		{
+			name:     "unused parameter",
+			template: "/assets/{id}",
+			params:   PathParams{"id": "1", "idd": "2"},
+			wantErr:  `path parameters ["idd"] have no placeholder`,
+		},
+		{
+			name:     "dot dot segment",
+			template: "/assets/{id}/delete",
+			params:   PathParams{"id": ".."},
+			wantErr:  `invalid value ".." for path parameter "id"`,
+		},
+		{
+			name:     "empty segment",
+			template: "/assets/{id}",
+			params:   PathParams{"id": ""},
+			wantErr:  `invalid value "" for path parameter "id"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandPath(tt.template, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExpandPath() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientPathParams(t *testing.T) {
+	var uris []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uris = append(uris, r.URL.RequestURI())
+		w.Write([]byte("<OK/>"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var routes []string
+	client.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			info, _ := RequestInfoFromContext(req.Context())
+			routes = append(routes, info.Route)
+			return next.RoundTrip(req)
+		})
+	})
+
+	ctx := WithPathParams(context.Background(), PathParams{"ref": "scan/1234.5678"})
+	if err := client.Get(ctx, "/scans/{ref}?action=fetch", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if uris[0] != "/scans/scan%2F1234.5678?action=fetch" {
+		t.Errorf("request URI = %q", uris[0])
+	}
+	if routes[0] != "/scans/{ref}" {
+		t.Errorf("route = %q, want the template", routes[0])
+	}
+
+	// An explicit route wins
+	if err := client.Get(WithRoute(ctx, "scan"), "/scans/{ref}", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if routes[1] != "scan" {
+		t.Errorf("route = %q, want scan", routes[1])
+	}
+
+	// Unbound placeholders fail before anything is sent
+	err := client.Get(WithPathParams(context.Background(), PathParams{}), "/scans/{ref}", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), `missing path parameter "ref"`) {
+		t.Errorf("Get() error = %v, want missing path parameter", err)
+	}
+	if len(uris) != 2 {
+		t.Errorf("server received %d requests, want 2", len(uris))
+	}
+
+	// Without PathParams braces are literal, as before templates existed
+	if err := client.Get(context.Background(), "/scans/{ref}?filter={id}", nil, nil); err != nil {
+		t.Fatalf("Get() of a literal path error = %v", err)
+	}
+	if got, _ := url.PathUnescape(uris[2]); got != "/scans/{ref}?filter={id}" {
+		t.Errorf("request URI = %q, want the literal path", uris[2])
+	}
+}
@@ -0,0 +1,79 @@
+package godefaultapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform is a Qualys cloud platform with its API server and API gateway
+type Platform struct {
+	// Name is the short platform name, e.g. "US3"
+	Name string
+	// APIURL is the API server used by the v2 FO and QPS REST APIs
+	APIURL string
+	// GatewayURL is the API gateway used by the newer JWT based APIs
+	GatewayURL string
+}
+
+// platforms are the Qualys platforms, see "Identify your Qualys platform"
+// in the Qualys documentation
+var platforms = []Platform{
+	{"US1", "https://qualysapi.qualys.com", "https://gateway.qg1.apps.qualys.com"},
+	{"US2", "https://qualysapi.qg2.apps.qualys.com", "https://gateway.qg2.apps.qualys.com"},
+	{"US3", "https://qualysapi.qg3.apps.qualys.com", "https://gateway.qg3.apps.qualys.com"},
+	{"US4", "https://qualysapi.qg4.apps.qualys.com", "https://gateway.qg4.apps.qualys.com"},
+	{"EU1", "https://qualysapi.qualys.eu", "https://gateway.qg1.apps.qualys.eu"},
+	{"EU2", "https://qualysapi.qg2.apps.qualys.eu", "https://gateway.qg2.apps.qualys.eu"},
+	{"EU3", "https://qualysapi.qg3.apps.qualys.it", "https://gateway.qg3.apps.qualys.it"},
+	{"IN1", "https://qualysapi.qg1.apps.qualys.in", "https://gateway.qg1.apps.qualys.in"},
+	{"CA1", "https://qualysapi.qg1.apps.qualys.ca", "https://gateway.qg1.apps.qualys.ca"},
+	{"AE1", "https://qualysapi.qg1.apps.qualys.ae", "https://gateway.qg1.apps.qualys.ae"},
+	{"UK1", "https://qualysapi.qg1.apps.qualys.co.uk", "https://gateway.qg1.apps.qualys.co.uk"},
+	{"AU1", "https://qualysapi.qg1.apps.qualys.com.au", "https://gateway.qg1.apps.qualys.com.au"},
+	{"KSA1", "https://qualysapi.qg1.apps.qualysksa.com", "https://gateway.qg1.apps.qualysksa.com"},
+}
+
+// Platforms returns the known Qualys platforms
+func Platforms() []Platform {
+	return append([]Platform(nil), platforms...)
+}
+
+// LookupPlatform returns the Qualys platform with the given name, ignoring case
+func LookupPlatform(name string) (Platform, error) {
+	names := make([]string, len(platforms))
+	for i, platform := range platforms {
+		if strings.EqualFold(platform.Name, name) {
+			return platform, nil
+		}
+		names[i] = platform.Name
+	}
+	return Platform{}, fmt.Errorf("unknown Qualys platform %q, known platforms are %s", name, strings.Join(names, ", "))
+}
+
+// ResolveBaseURL returns the API server URL of a platform name such as
+// "US3", or nameOrURL itself if it is already a URL
+func ResolveBaseURL(nameOrURL string) (string, error) {
+	if strings.Contains(nameOrURL, "://") {
+		return nameOrURL, nil
+	}
+	platform, err := LookupPlatform(nameOrURL)
+	if err != nil {
+		return "", err
+	}
+	return platform.APIURL, nil
+}
+
+// SetPlatforms sets the API servers of the named Qualys platforms, or
+// explicit URLs, as the client's base URLs in order of preference
+func (c *Client) SetPlatforms(namesOrURLs ...string) error {
+	urls := make([]string, len(namesOrURLs))
+	for i, nameOrURL := range namesOrURLs {
+		url, err := ResolveBaseURL(nameOrURL)
+		if err != nil {
+			return err
+		}
+		urls[i] = url
+	}
+	c.SetBaseURLs(urls...)
+	return nil
+}
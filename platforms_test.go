@@ -0,0 +1,31 @@
+package godefaultapi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlatforms(t *testing.T) {
+	platform, err := LookupPlatform("us3")
+	if err != nil || platform.APIURL != "https://qualysapi.qg3.apps.qualys.com" || platform.GatewayURL != "https://gateway.qg3.apps.qualys.com" {
+		t.Errorf("LookupPlatform(us3) = %+v, %v", platform, err)
+	}
+	if _, err := LookupPlatform("MARS1"); err == nil || !strings.Contains(err.Error(), "US1, US2") {
+		t.Errorf("LookupPlatform(MARS1) error = %v", err)
+	}
+	if url, err := ResolveBaseURL("https://qualysapi.example.com"); err != nil || url != "https://qualysapi.example.com" {
+		t.Errorf("ResolveBaseURL(url) = %q, %v", url, err)
+	}
+
+	client := NewClient("")
+	if err := client.SetPlatforms("US3", "EU1"); err != nil {
+		t.Fatalf("SetPlatforms() error = %v", err)
+	}
+	want := []string{"https://qualysapi.qg3.apps.qualys.com", "https://qualysapi.qualys.eu"}
+	if got := client.BaseURLs(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("BaseURLs() = %v, want %v", got, want)
+	}
+	if err := client.SetPlatforms("US3", "nowhere"); err == nil {
+		t.Error("SetPlatforms(nowhere) error = nil")
+	}
+}
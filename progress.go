@@ -0,0 +1,163 @@
+package godefaultapi
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// ProgressReporter receives progress updates from long running operations.
+// A total of -1 means the total is not known.
+type ProgressReporter interface {
+	// Start is called once before any progress is made
+	Start(total int64)
+	// SetTotal is called when the total becomes known or changes
+	SetTotal(total int64)
+	// Advance reports n more units of work completed
+	Advance(n int64)
+	// Finish is called once when the operation ends
+	Finish()
+}
+
+// NopProgressReporter discards all progress updates
+type NopProgressReporter struct{}
+
+func (NopProgressReporter) Start(int64)    {}
+func (NopProgressReporter) SetTotal(int64) {}
+func (NopProgressReporter) Advance(int64)  {}
+func (NopProgressReporter) Finish()        {}
+
+// ProgressBarReporter renders progress as a terminal progress bar
+type ProgressBarReporter struct {
+	writer      io.Writer
+	description string
+	showBytes   bool
+	bar         *progressbar.ProgressBar
+}
+
+// NewProgressBarReporter creates a progress bar that renders to w, usually os.Stderr
+func NewProgressBarReporter(w io.Writer, description string) *ProgressBarReporter {
+	return &ProgressBarReporter{
+		writer:      w,
+		description: description,
+	}
+}
+
+// SetShowBytes formats progress as byte counts, for downloads
+func (r *ProgressBarReporter) SetShowBytes(showBytes bool) {
+	r.showBytes = showBytes
+}
+
+// Start implements ProgressReporter
+func (r *ProgressBarReporter) Start(total int64) {
+	r.bar = progressbar.NewOptions64(total,
+		progressbar.OptionSetWriter(r.writer),
+		progressbar.OptionSetDescription(r.description),
+		progressbar.OptionShowBytes(r.showBytes),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(10),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetRenderBlankState(true),
+	)
+}
+
+// SetTotal implements ProgressReporter
+func (r *ProgressBarReporter) SetTotal(total int64) {
+	if r.bar != nil {
+		r.bar.ChangeMax64(total)
+	}
+}
+
+// Advance implements ProgressReporter
+func (r *ProgressBarReporter) Advance(n int64) {
+	if r.bar != nil {
+		r.bar.Add64(n)
+	}
+}
+
+// Finish implements ProgressReporter
+func (r *ProgressBarReporter) Finish() {
+	if r.bar != nil {
+		r.bar.Finish()
+		fmt.Fprintln(r.writer)
+	}
+}
+
+// SlogProgressReporter logs progress with a structured logger, at most once
+// per interval
+type SlogProgressReporter struct {
+	logger    *slog.Logger
+	operation string
+	interval  time.Duration
+
+	mu        sync.Mutex
+	total     int64
+	completed int64
+	started   time.Time
+	lastLog   time.Time
+}
+
+// NewSlogProgressReporter creates a reporter that logs progress of operation
+// every 5 seconds
+func NewSlogProgressReporter(logger *slog.Logger, operation string) *SlogProgressReporter {
+	return &SlogProgressReporter{
+		logger:    logger,
+		operation: operation,
+		interval:  5 * time.Second,
+	}
+}
+
+// SetInterval sets the minimum time between progress log entries
+func (r *SlogProgressReporter) SetInterval(interval time.Duration) {
+	r.interval = interval
+}
+
+// Start implements ProgressReporter
+func (r *SlogProgressReporter) Start(total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total = total
+	r.completed = 0
+	r.started = time.Now()
+	r.lastLog = r.started
+	r.logger.Info("operation started", slog.String("operation", r.operation), slog.Int64("total", total))
+}
+
+// SetTotal implements ProgressReporter
+func (r *SlogProgressReporter) SetTotal(total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total = total
+}
+
+// Advance implements ProgressReporter
+func (r *SlogProgressReporter) Advance(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.completed += n
+	if now := time.Now(); now.Sub(r.lastLog) >= r.interval {
+		r.lastLog = now
+		r.logger.Info("operation progress",
+			slog.String("operation", r.operation),
+			slog.Int64("completed", r.completed),
+			slog.Int64("total", r.total),
+		)
+	}
+}
+
+// Finish implements ProgressReporter
+func (r *SlogProgressReporter) Finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logger.Info("operation finished",
+		slog.String("operation", r.operation),
+		slog.Int64("completed", r.completed),
+		slog.Duration("duration", time.Since(r.started)),
+	)
+}
@@ -0,0 +1,78 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgressBarReporter(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 4096)
+	server, _ := downloadServer(t, content, 0)
+
+	var out bytes.Buffer
+	progress := NewProgressBarReporter(&out, "report")
+	progress.SetShowBytes(true)
+	client := NewClient(server.URL)
+	if _, err := client.DownloadFile(context.Background(), "/report", filepath.Join(t.TempDir(), "report.csv"), progress); err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+
+	state := progress.bar.State()
+	if state.CurrentNum != int64(len(content)) || state.Max != int64(len(content)) {
+		t.Errorf("bar = %d/%d, want %d/%d", state.CurrentNum, state.Max, len(content), len(content))
+	}
+	if !progress.bar.IsFinished() {
+		t.Error("bar not finished")
+	}
+	if got := out.String(); !strings.Contains(got, "report") || !strings.Contains(got, "100%") || !strings.HasSuffix(got, "\n") {
+		t.Errorf("output = %q, want a finished report bar", got)
+	}
+}
+
+func TestSlogProgressReporter(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 4096)
+	server, _ := downloadServer(t, content, 1)
+
+	var out bytes.Buffer
+	progress := NewSlogProgressReporter(slog.New(slog.NewJSONHandler(&out, nil)), "report")
+	progress.SetInterval(0)
+	client := NewClient(server.URL)
+	if _, err := client.DownloadFile(context.Background(), "/report", filepath.Join(t.TempDir(), "report.csv"), progress); err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+
+	type entry struct {
+		Msg       string `json:"msg"`
+		Operation string `json:"operation"`
+		Completed int64  `json:"completed"`
+		Total     int64  `json:"total"`
+	}
+	var entries []entry
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) < 3 {
+		t.Fatalf("logged %d entries, want start, progress and finish", len(entries))
+	}
+
+	first, last := entries[0], entries[len(entries)-1]
+	if first.Msg != "operation started" || first.Total != -1 {
+		t.Errorf("first entry = %+v, want start with unknown total", first)
+	}
+	if last.Msg != "operation finished" || last.Operation != "report" || last.Completed != int64(len(content)) {
+		t.Errorf("last entry = %+v, want finish with %d bytes", last, len(content))
+	}
+	progressed := entries[len(entries)-2]
+	if progressed.Msg != "operation progress" || progressed.Completed != int64(len(content)) || progressed.Total != int64(len(content)) {
+		t.Errorf("final progress = %+v, want %d of %d", progressed, len(content), len(content))
+	}
+}
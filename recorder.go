@@ -0,0 +1,281 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNoRecordedResponse is returned in replay mode when no recorded
+// interaction matches a request
+var ErrNoRecordedResponse = errors.New("no recorded response matches request")
+
+// RecorderMode selects whether a Recorder records or replays
+type RecorderMode int
+
+const (
+	// RecorderModeRecord sends requests and records every interaction
+	RecorderModeRecord RecorderMode = iota
+	// RecorderModeReplay serves responses from the cassette without sending requests
+	RecorderModeReplay
+	// RecorderModeReplayOrRecord replays matching interactions and records new ones
+	RecorderModeReplayOrRecord
+)
+
+// MatchOn selects the request fields compared when replaying
+type MatchOn int
+
+const (
+	// MatchMethod compares the HTTP method
+	MatchMethod MatchOn = 1 << iota
+	// MatchPath compares the URL path
+	MatchPath
+	// MatchQuery compares the query parameters, ignoring order
+	MatchQuery
+	// MatchBody compares the request body
+	MatchBody
+
+	// DefaultMatch compares method, path and query
+	DefaultMatch = MatchMethod | MatchPath | MatchQuery
+)
+
+// Cassette is a recorded sequence of interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request with credentials scrubbed
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded response with credentials scrubbed
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder records request/response pairs to a cassette file and replays
+// them, so that code built on Client can be tested offline
+type Recorder struct {
+	path  string
+	mode  RecorderMode
+	match MatchOn
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder creates a recorder for the cassette at path. The cassette is
+// loaded in the replay modes and must exist in RecorderModeReplay.
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{
+		path:  path,
+		mode:  mode,
+		match: DefaultMatch,
+	}
+	if mode == RecorderModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && mode == RecorderModeReplayOrRecord {
+			return r, nil
+		}
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("error decoding cassette: %w", err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// SetMatch sets the request fields compared when replaying
+func (r *Recorder) SetMatch(match MatchOn) {
+	r.match = match
+}
+
+// Interactions returns the interactions recorded or loaded so far
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Middleware returns the middleware that records or replays requests
+func (r *Recorder) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			recorded := scrubRequest(req, body)
+
+			if r.mode != RecorderModeRecord {
+				if interaction, ok := r.find(recorded); ok {
+					return interaction.Response.response(req), nil
+				}
+				if r.mode == RecorderModeReplay {
+					return nil, fmt.Errorf("%w: %s %s", ErrNoRecordedResponse, recorded.Method, recorded.URL)
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			respBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+			if err := r.record(Interaction{
+				Request: recorded,
+				Response: RecordedResponse{
+					StatusCode: resp.StatusCode,
+					Header:     redactHeaders(req.Context(), resp.Header),
+					Body:       string(respBody),
+				},
+			}); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// find returns the first unused matching interaction, or the last matching
+// one if all have been used
+func (r *Recorder) find(req RecordedRequest) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.matches(req, interaction.Request) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return r.cassette.Interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+// matches compares a request with a recorded request
+func (r *Recorder) matches(req, recorded RecordedRequest) bool {
+	if r.match&MatchMethod != 0 && req.Method != recorded.Method {
+		return false
+	}
+	if r.match&MatchBody != 0 && req.Body != recorded.Body {
+		return false
+	}
+
+	reqURL, err := url.Parse(req.URL)
+	if err != nil {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	if r.match&MatchPath != 0 && reqURL.Path != recordedURL.Path {
+		return false
+	}
+	if r.match&MatchQuery != 0 && reqURL.Query().Encode() != recordedURL.Query().Encode() {
+		return false
+	}
+	return true
+}
+
+// record appends an interaction and saves the cassette
+func (r *Recorder) record(interaction Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.used = append(r.used, true)
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+	if err := writeFileAtomic(r.path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+	return nil
+}
+
+// response builds an HTTP response from the recorded response
+func (rr RecordedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rr.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody reads the request body and restores it for sending
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// scrubRequest returns the recorded form of a request with credentials
+// removed from the URL, headers and form bodies
+func scrubRequest(req *http.Request, body []byte) RecordedRequest {
+	recordedBody := string(body)
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(recordedBody); err == nil {
+			recordedBody = redactValues(req.Context(), form).Encode()
+		}
+	}
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    redactURL(req.Context(), req.URL.String()),
+		Header: redactHeaders(req.Context(), req.Header),
+		Body:   recordedBody,
+	}
+}
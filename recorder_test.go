@@ -0,0 +1,81 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "QualysSession=abc")
+		w.Write([]byte(`{"name":"` + r.URL.Query().Get("action") + `"}`))
+	}))
+	cassette := filepath.Join(t.TempDir(), "users.json")
+
+	recorder, err := NewRecorder(cassette, RecorderModeRecord)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetBasicAuth("user", "hunter2")
+	client.Use(recorder.Middleware())
+
+	var recorded cacheItem
+	if err := client.Get(context.Background(), "/msp/user.php?action=list&password=hunter2", nil, &recorded); err != nil {
+		t.Fatalf("Get() while recording error = %v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("reading cassette: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "dXNlcjpodW50ZXIy", "QualysSession=abc"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	replayer, err := NewRecorder(cassette, RecorderModeReplay)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client = NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetBasicAuth("user", "hunter2")
+	client.Use(replayer.Middleware())
+
+	var replayed cacheItem
+	if err := client.Get(context.Background(), "/msp/user.php?password=hunter2&action=list", nil, &replayed); err != nil {
+		t.Fatalf("Get() while replaying error = %v", err)
+	}
+	if replayed.Name != "list" {
+		t.Errorf("replayed name = %q, want list", replayed.Name)
+	}
+
+	err = client.Get(context.Background(), "/msp/user.php?action=add", nil, &replayed)
+	if !errors.Is(err, ErrNoRecordedResponse) {
+		t.Errorf("unmatched request error = %v, want ErrNoRecordedResponse", err)
+	}
+}
+
+func TestRecorderMatchBody(t *testing.T) {
+	recorder := &Recorder{match: DefaultMatch | MatchBody}
+	recorder.cassette.Interactions = []Interaction{
+		{Request: RecordedRequest{Method: "POST", URL: "http://x/search", Body: "a"}, Response: RecordedResponse{StatusCode: 200, Body: "first"}},
+		{Request: RecordedRequest{Method: "POST", URL: "http://x/search", Body: "b"}, Response: RecordedResponse{StatusCode: 200, Body: "second"}},
+	}
+	recorder.used = make([]bool, 2)
+
+	got, ok := recorder.find(RecordedRequest{Method: "POST", URL: "http://x/search", Body: "b"})
+	if !ok || got.Response.Body != "second" {
+		t.Errorf("find() = %+v, %v, want the second interaction", got, ok)
+	}
+}
@@ -0,0 +1,31 @@
+package godefaultapi
+
+import (
+	"context"
+	"strings"
+)
+
+// routeKey is the context key for the route template of a call
+type routeKey struct{}
+
+// WithRoute returns a context that reports route as the route template of
+// calls made with it, e.g. "/qps/rest/2.0/get/am/hostasset/{id}". Tracing and
+// metrics use the route instead of the raw path to keep cardinality bounded.
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// routeFor returns the route template for a call, falling back to path with
+// its query string removed
+func routeFor(ctx context.Context, path string) string {
+	if route, ok := ctx.Value(routeKey{}).(string); ok && route != "" {
+		return route
+	}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,211 @@
+package godefaultapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SessionConfig describes a form based session login API
+type SessionConfig struct {
+	// LoginPath is the path the login form is posted to
+	LoginPath string
+	// LoginForm is the login form, usually including the credentials
+	LoginForm url.Values
+	// LogoutPath is the path the logout form is posted to. Empty skips logout.
+	LogoutPath string
+	// LogoutForm is the logout form
+	LogoutForm url.Values
+	// CookieName is the session cookie set by a successful login. A stored
+	// session cookie, e.g. from a FileCookieJar, is reused instead of logging in.
+	CookieName string
+	// KeepSession skips the logout at the end of Run so that a persisted
+	// cookie jar can reuse the session in a later run
+	KeepSession bool
+}
+
+// QualysSessionConfig returns the session configuration for the Qualys v2
+// FO session API, /api/2.0/fo/session/
+func QualysSessionConfig(username, password string) *SessionConfig {
+	return &SessionConfig{
+		LoginPath:  "/api/2.0/fo/session/",
+		LoginForm:  url.Values{"action": {"login"}, "username": {username}, "password": {password}},
+		LogoutPath: "/api/2.0/fo/session/",
+		LogoutForm: url.Values{"action": {"logout"}},
+		CookieName: "QualysSession",
+	}
+}
+
+// SessionAuthenticator authenticates requests with a session cookie obtained
+// by logging in once, instead of sending credentials on every call. It logs
+// in on first use, and again when the session cookie has expired or a call
+// is rejected with 401 Unauthorized, which is then sent once more.
+type SessionAuthenticator struct {
+	client *Client
+	config *SessionConfig
+
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+// sessionRenewer is an Authenticator whose session can expire and be renewed
+type sessionRenewer interface {
+	renew(ctx context.Context, req *http.Request) error
+}
+
+// sessionRequestKey is the context key marking login and logout requests
+type sessionRequestKey struct{}
+
+// NewSessionAuthenticator creates a session authenticator for client and
+// sets it as the client's authenticator. A cookie jar is added to the client
+// if it has none.
+func NewSessionAuthenticator(client *Client, config *SessionConfig) *SessionAuthenticator {
+	if client.httpClient.Jar == nil {
+		jar, _ := cookiejar.New(nil)
+		client.SetCookieJar(jar)
+	}
+	s := &SessionAuthenticator{client: client, config: config}
+	client.SetAuthenticator(s)
+	return s
+}
+
+// Authenticate implements Authenticator
+func (s *SessionAuthenticator) Authenticate(req *http.Request, body []byte) error {
+	if req.Context().Value(sessionRequestKey{}) != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active(req.URL) {
+		return nil
+	}
+	return s.login(req.Context())
+}
+
+// renew logs in again after req was rejected as unauthorized, unless another
+// call already renewed the session
+func (s *SessionAuthenticator) renew(ctx context.Context, req *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.CookieName != "" {
+		sent, _ := req.Cookie(s.config.CookieName)
+		if current := s.sessionCookie(req.URL); current != nil && (sent == nil || sent.Value != current.Value) {
+			return nil
+		}
+	}
+	return s.login(ctx)
+}
+
+// Login logs in, replacing any existing session
+func (s *SessionAuthenticator) Login(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.login(ctx)
+}
+
+// Logout ends the session
+func (s *SessionAuthenticator) Logout(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config.LogoutPath == "" {
+		s.loggedIn = false
+		return nil
+	}
+	if err := s.post(ctx, s.config.LogoutPath, s.config.LogoutForm); err != nil {
+		return fmt.Errorf("error logging out: %w", err)
+	}
+	s.loggedIn = false
+	return nil
+}
+
+// Run logs in if there is no session, runs fn and then logs out, unless
+// the configuration keeps the session
+func (s *SessionAuthenticator) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	s.mu.Lock()
+	if !s.active(s.loginURL()) {
+		if err := s.login(ctx); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Unlock()
+
+	err := fn(ctx)
+	if s.config.KeepSession {
+		return err
+	}
+	if logoutErr := s.Logout(ctx); err == nil {
+		err = logoutErr
+	}
+	return err
+}
+
+// login posts the login form. s.mu must be held.
+func (s *SessionAuthenticator) login(ctx context.Context) error {
+	if err := s.post(ctx, s.config.LoginPath, s.config.LoginForm); err != nil {
+		return fmt.Errorf("error logging in: %w", err)
+	}
+	if s.config.CookieName != "" && s.sessionCookie(s.loginURL()) == nil {
+		return fmt.Errorf("error logging in: no %s cookie in response", s.config.CookieName)
+	}
+	s.loggedIn = true
+	return nil
+}
+
+// post sends a form to path without authenticating it. Logging in or out
+// twice is harmless, so the form is safe to re-send.
+func (s *SessionAuthenticator) post(ctx context.Context, path string, form url.Values) error {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx = context.WithValue(withHeaders(ctx, header), sessionRequestKey{}, true)
+	ctx = WithRetrySafe(ctx, true)
+
+	body := []byte(form.Encode())
+	_, err := s.client.call(ctx, http.MethodPost, path, body, func(resp *http.Response) ([]byte, error) {
+		if resp.StatusCode >= 400 {
+			return responseError(resp)
+		}
+		io.Copy(io.Discard, resp.Body)
+		return nil, nil
+	})
+	return err
+}
+
+// active reports whether there is a session for requests to u. Without a
+// cookie name a login lasts until logout. s.mu must be held.
+func (s *SessionAuthenticator) active(u *url.URL) bool {
+	if s.config.CookieName == "" {
+		return s.loggedIn
+	}
+	return s.sessionCookie(u) != nil
+}
+
+// sessionCookie returns the session cookie the client's cookie jar sends to
+// u, or nil if there is none
+func (s *SessionAuthenticator) sessionCookie(u *url.URL) *http.Cookie {
+	jar := s.client.httpClient.Jar
+	if s.config.CookieName == "" || jar == nil || u == nil {
+		return nil
+	}
+	for _, cookie := range jar.Cookies(u) {
+		if cookie.Name == s.config.CookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// loginURL returns the login URL at the base URL currently in use
+func (s *SessionAuthenticator) loginURL() *url.URL {
+	u, err := url.Parse(strings.TrimSuffix(s.client.pickBaseURL(), "/") + s.config.LoginPath)
+	if err != nil {
+		return nil
+	}
+	return u
+}
@@ -0,0 +1,195 @@
+package godefaultapi
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sessionServer is a fake Qualys session API
+type sessionServer struct {
+	mu      sync.Mutex
+	logins  int
+	logouts int
+	calls   int
+	// expired makes the server reject the current session until the next login
+	expired bool
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/api/2.0/fo/session/" {
+		r.ParseForm()
+		switch r.PostForm.Get("action") {
+		case "login":
+			if r.PostForm.Get("username") != "user" || r.PostForm.Get("password") != "pass" {
+				http.Error(w, "bad login", http.StatusUnauthorized)
+				return
+			}
+			s.logins++
+			s.expired = false
+			http.SetCookie(w, &http.Cookie{Name: "QualysSession", Value: "s3ss10n", Path: "/api"})
+		case "logout":
+			s.logouts++
+			http.SetCookie(w, &http.Cookie{Name: "QualysSession", Path: "/api", MaxAge: -1})
+		}
+		w.Write([]byte("<SIMPLE_RETURN/>"))
+		return
+	}
+
+	if cookie, err := r.Cookie("QualysSession"); err != nil || cookie.Value != "s3ss10n" || s.expired {
+		http.Error(w, "no session", http.StatusUnauthorized)
+		return
+	}
+	s.calls++
+	w.Write([]byte("<OK/>"))
+}
+
+func TestSessionAuthenticator(t *testing.T) {
+	fake := &sessionServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := NewClient(server.URL)
+	client.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	session := NewSessionAuthenticator(client, QualysSessionConfig("user", "pass"))
+
+	err := session.Run(context.Background(), func(ctx context.Context) error {
+		for i := 0; i < 3; i++ {
+			if err := client.Get(ctx, "/api/2.0/fo/scan/", nil, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if fake.logins != 1 || fake.logouts != 1 || fake.calls != 3 {
+		t.Errorf("logins = %d, logouts = %d, calls = %d, want 1, 1, 3", fake.logins, fake.logouts, fake.calls)
+	}
+	if strings.Contains(logs.String(), "password=pass") {
+		t.Error("login password was logged")
+	}
+
+	// Authenticate logs in again on demand after logout
+	if err := client.Get(context.Background(), "/api/2.0/fo/scan/", nil, nil); err != nil {
+		t.Fatalf("Get() after logout error = %v", err)
+	}
+	if fake.logins != 2 {
+		t.Errorf("logins = %d, want 2", fake.logins)
+	}
+}
+
+func TestSessionAuthenticatorBadCredentials(t *testing.T) {
+	server := httptest.NewServer(&sessionServer{})
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	NewSessionAuthenticator(client, QualysSessionConfig("user", "wrong"))
+	err := client.Get(context.Background(), "/api/2.0/fo/scan/", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "error logging in") {
+		t.Fatalf("Get() error = %v, want login error", err)
+	}
+}
+
+func TestSessionAuthenticatorReusesPersistedSession(t *testing.T) {
+	fake := &sessionServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	config := QualysSessionConfig("user", "pass")
+	config.KeepSession = true
+	for run := 0; run < 2; run++ {
+		jar, err := NewFileCookieJar(path)
+		if err != nil {
+			t.Fatalf("NewFileCookieJar() error = %v", err)
+		}
+		client := NewClient(server.URL)
+		client.SetCookieJar(jar)
+		session := NewSessionAuthenticator(client, config)
+		err = session.Run(context.Background(), func(ctx context.Context) error {
+			return client.Get(ctx, "/api/2.0/fo/scan/", nil, nil)
+		})
+		if err != nil {
+			t.Fatalf("run %d: Run() error = %v", run, err)
+		}
+		if err := jar.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if fake.logins != 1 || fake.logouts != 0 || fake.calls != 2 {
+		t.Errorf("logins = %d, logouts = %d, calls = %d, want 1, 0, 2", fake.logins, fake.logouts, fake.calls)
+	}
+}
+
+func TestSessionAuthenticatorRenewsExpiredSession(t *testing.T) {
+	fake := &sessionServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	NewSessionAuthenticator(client, QualysSessionConfig("user", "pass"))
+	get := func() {
+		t.Helper()
+		if err := client.Get(context.Background(), "/api/2.0/fo/scan/", nil, nil); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	get()
+
+	// The server times the session out and rejects it with 401
+	fake.mu.Lock()
+	fake.expired = true
+	fake.mu.Unlock()
+	get()
+	if fake.logins != 2 || fake.calls != 2 {
+		t.Errorf("after 401: logins = %d, calls = %d, want 2, 2", fake.logins, fake.calls)
+	}
+
+	// The session cookie expires in the jar
+	u, _ := url.Parse(server.URL + "/api")
+	client.httpClient.Jar.SetCookies(u, []*http.Cookie{{Name: "QualysSession", Path: "/api", MaxAge: -1}})
+	get()
+	if fake.logins != 3 || fake.calls != 3 {
+		t.Errorf("after cookie expiry: logins = %d, calls = %d, want 3, 3", fake.logins, fake.calls)
+	}
+}
+
+func TestSessionAuthenticatorAfterFailover(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	fake := &sessionServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	// Cookies ignore ports, so the failed base needs another host name
+	downURL := strings.Replace(down.URL, "127.0.0.1", "localhost", 1)
+	client := NewClient(downURL)
+	client.SetBaseURLs(downURL, server.URL)
+	session := NewSessionAuthenticator(client, QualysSessionConfig("user", "pass"))
+	err := session.Run(context.Background(), func(ctx context.Context) error {
+		for i := 0; i < 2; i++ {
+			if err := client.Get(ctx, "/api/2.0/fo/scan/", nil, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if fake.logins != 1 || fake.calls != 2 {
+		t.Errorf("logins = %d, calls = %d, want 1, 2", fake.logins, fake.calls)
+	}
+}
@@ -0,0 +1,84 @@
+package godefaultapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces concurrent calls with the same key
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-flight or completed call
+type flightCall struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+// SetSingleFlight enables or disables coalescing of identical concurrent GET
+// requests. While enabled, callers that issue a GET for the same URL, body,
+// headers and response type while one is in flight share its single HTTP
+// call, and the response is decoded into each caller's result. A failure of the shared
+// call, including cancellation of the first caller's context, is returned to
+// every caller.
+func (c *Client) SetSingleFlight(enabled bool) {
+	if !enabled {
+		c.flights = nil
+		return
+	}
+	if c.flights == nil {
+		c.flights = &flightGroup{calls: make(map[string]*flightCall)}
+	}
+}
+
+// flightKey identifies identical requests. Calls share a flight only if they
+// go to the same base URL with the same per-call headers and cache TTL, so no
+// caller receives data fetched with another caller's credentials.
+func (c *Client) flightKey(ctx context.Context, method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write(body)
+	headersFromContext(ctx).Write(h)
+	if ttl, ok := ctx.Value(cacheTTLKey{}).(time.Duration); ok {
+		fmt.Fprintf(h, "ttl=%d", ttl)
+	}
+	return method + " " + c.pickBaseURL() + path + "\n" + string(c.responseTypeFor(ctx)) + "\n" + hex.EncodeToString(h.Sum(nil))
+}
+
+// do runs fn once for all concurrent callers with the same key. shared
+// reports whether the result came from another caller's call.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) (body []byte, shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.body, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	// Reported to waiting callers if fn panics
+	call.err = errors.New("single-flight call did not complete")
+	call.body, call.err = fn()
+	return call.body, false, call.err
+}
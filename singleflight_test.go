@@ -0,0 +1,143 @@
+package godefaultapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleFlightCoalescesGets(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte(`{"name":"users"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetSingleFlight(true)
+
+	const callers = 5
+	results := make([]cacheItem, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Get(context.Background(), "/msp/user_list.php", nil, &results[i])
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("hits = %d, want 1", got)
+	}
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Errorf("caller %d error = %v", i, errs[i])
+		}
+		if results[i].Name != "users" {
+			t.Errorf("caller %d name = %q, want users", i, results[i].Name)
+		}
+	}
+}
+
+func TestSingleFlightIgnoresPosts(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetSingleFlight(true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Post(context.Background(), "/msp/user.php", []byte("a=1"), nil)
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("hits = %d, want 3", got)
+	}
+}
+
+func TestSingleFlightKey(t *testing.T) {
+	client := NewClient("https://primary.example.com")
+	client.SetBaseURLs("https://primary.example.com", "https://secondary.example.com")
+	ctx := context.Background()
+	key := client.flightKey(ctx, http.MethodGet, "/scans", nil)
+
+	if got := client.flightKey(ctx, http.MethodGet, "/scans", nil); got != key {
+		t.Errorf("identical calls have different keys")
+	}
+	differ := map[string]string{
+		"per-call header": client.flightKey(withHeaders(ctx, http.Header{"Authorization": {"Bearer other"}}), http.MethodGet, "/scans", nil),
+		"cache TTL":       client.flightKey(WithCacheTTL(ctx, 0), http.MethodGet, "/scans", nil),
+	}
+	client.basePool.markDown("https://primary.example.com")
+	differ["base URL"] = client.flightKey(ctx, http.MethodGet, "/scans", nil)
+	for name, got := range differ {
+		if got == key {
+			t.Errorf("calls differing by %s share a key", name)
+		}
+	}
+}
+
+func TestSingleFlightNoContent(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetSingleFlight(true)
+
+	const callers = 5
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result struct{}
+			errs[i] = client.Get(context.Background(), "/msp/user.php", nil, &result)
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("hits = %d, want 1", got)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("caller %d error = %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package godefaultapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans. Implement it to bridge to OpenTelemetry or another
+// tracing system, or use NewTracer with a SpanExporter.
+type Tracer interface {
+	// Start starts a span that is a child of the span in ctx, if any
+	Start(ctx context.Context, name string) Span
+}
+
+// Span is a single timed operation
+type Span interface {
+	// SetAttribute records a key/value pair on the span
+	SetAttribute(key string, value interface{})
+	// RecordError marks the span as failed
+	RecordError(err error)
+	// End completes the span
+	End()
+	// TraceParent returns the W3C traceparent header value for the span,
+	// or an empty string if the span should not be propagated
+	TraceParent() string
+}
+
+// spanKey is the context key for the current span
+type spanKey struct{}
+
+// SetTracer sets the tracer used to emit a span per call and per attempt.
+// Each attempt's trace context is propagated in the traceparent header.
+func (c *Client) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
+// ContextWithSpan returns a context carrying span as the parent for spans
+// started by the client
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithTraceParent returns a context whose spans continue the trace
+// described by a W3C traceparent header value, e.g. from an incoming request
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if _, _, ok := parseTraceParent(traceParent); !ok {
+		return ctx
+	}
+	return ContextWithSpan(ctx, remoteSpan(traceParent))
+}
+
+// spanFromContext returns the current span in ctx, or nil
+func spanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// startSpan starts a span with the client's tracer and stores it in the
+// returned context. Without a tracer a no-op span is returned.
+func (c *Client) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	span := c.tracer.Start(ctx, name)
+	return ContextWithSpan(ctx, span), span
+}
+
+// noopSpan is used when no tracer is configured
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
+func (noopSpan) TraceParent() string              { return "" }
+
+// remoteSpan is a parent span received from another process
+type remoteSpan string
+
+func (remoteSpan) SetAttribute(string, interface{}) {}
+func (remoteSpan) RecordError(error)                {}
+func (remoteSpan) End()                             {}
+func (s remoteSpan) TraceParent() string            { return string(s) }
+
+// SpanData is a completed span as handed to a SpanExporter
+type SpanData struct {
+	Name         string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]interface{}
+	Err          error
+}
+
+// Duration returns how long the span lasted
+func (d SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// SpanExporter receives spans when they end
+type SpanExporter interface {
+	ExportSpan(span SpanData)
+}
+
+// NewTracer creates a tracer that generates W3C trace context identifiers
+// and hands every finished span to exporter
+func NewTracer(exporter SpanExporter) Tracer {
+	return &tracer{exporter: exporter}
+}
+
+// tracer is the built-in Tracer implementation
+type tracer struct {
+	exporter SpanExporter
+}
+
+// Start implements Tracer
+func (t *tracer) Start(ctx context.Context, name string) Span {
+	s := &span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			SpanID:     randomHex(8),
+			Start:      time.Now(),
+			Attributes: make(map[string]interface{}),
+		},
+	}
+	if parent := spanFromContext(ctx); parent != nil {
+		if traceID, spanID, ok := parseTraceParent(parent.TraceParent()); ok {
+			s.data.TraceID = traceID
+			s.data.ParentSpanID = spanID
+		}
+	}
+	if s.data.TraceID == "" {
+		s.data.TraceID = randomHex(16)
+	}
+	return s
+}
+
+// span is the built-in Span implementation
+type span struct {
+	tracer *tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SetAttribute implements Span
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// RecordError implements Span
+func (s *span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+// End implements Span
+func (s *span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+// TraceParent implements Span
+func (s *span) TraceParent() string {
+	return "00-" + s.data.TraceID + "-" + s.data.SpanID + "-01"
+}
+
+// InMemoryExporter collects spans in memory, for use in tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty in-memory exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan implements SpanExporter
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the spans exported so far, in the order they ended
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset discards all collected spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// parseTraceParent extracts the trace and span IDs from a traceparent value
+func parseTraceParent(traceParent string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// randomHex returns n random bytes hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package godefaultapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTracerEmitsCallAndAttemptSpans(t *testing.T) {
+	var traceParents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParents = append(traceParents, r.Header.Get("Traceparent"))
+		if len(traceParents) == 1 {
+			w.Header().Set("X-RateLimit-Reset", "soon")
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	client := NewClient(server.URL)
+	client.SetResponseType(ContentTypeJSON)
+	client.SetTracer(NewTracer(exporter))
+	client.SetRateLimitConfig(&RateLimitConfig{
+		HeaderName:      "X-RateLimit-Reset",
+		MaxRetries:      3,
+		DefaultWaitTime: time.Millisecond,
+	})
+
+	parent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := ContextWithTraceParent(context.Background(), parent)
+	var result map[string]interface{}
+	if err := client.Get(ctx, "/msp/user_list.php?action=list", nil, &result); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	first, second, call := spans[0], spans[1], spans[2]
+
+	if call.Name != "GET /msp/user_list.php" {
+		t.Errorf("call span name = %q", call.Name)
+	}
+	if call.TraceID != "0af7651916cd43dd8448eb211c80319c" || call.ParentSpanID != "b7ad6b7169203331" {
+		t.Errorf("call span did not continue the remote trace: %+v", call)
+	}
+	if call.Attributes["retry_count"] != 1 || call.Attributes["http.status_code"] != 200 {
+		t.Errorf("call span attributes = %v", call.Attributes)
+	}
+	if call.Attributes["rate_limit.wait_ms"] != int64(1) {
+		t.Errorf("call span rate_limit.wait_ms = %v, want 1", call.Attributes["rate_limit.wait_ms"])
+	}
+
+	for i, attempt := range []SpanData{first, second} {
+		if attempt.ParentSpanID != call.SpanID || attempt.TraceID != call.TraceID {
+			t.Errorf("attempt %d is not a child of the call span", i+1)
+		}
+		if attempt.Attributes["attempt"] != i+1 {
+			t.Errorf("attempt %d attribute = %v", i+1, attempt.Attributes["attempt"])
+		}
+		want := "00-" + attempt.TraceID + "-" + attempt.SpanID + "-01"
+		if traceParents[i] != want {
+			t.Errorf("attempt %d traceparent = %q, want %q", i+1, traceParents[i], want)
+		}
+	}
+	if _, ok := first.Attributes["rate_limit.wait_ms"]; ok {
+		t.Error("first attempt should not record a rate limit wait")
+	}
+	if second.Attributes["rate_limit.wait_ms"] != int64(1) {
+		t.Errorf("second attempt rate_limit.wait_ms = %v, want 1", second.Attributes["rate_limit.wait_ms"])
+	}
+}
+
+func TestRouteFor(t *testing.T) {
+	ctx := context.Background()
+	if got := routeFor(ctx, "/api/2.0/fo/scan/?action=list"); got != "/api/2.0/fo/scan/" {
+		t.Errorf("routeFor() = %q", got)
+	}
+	ctx = WithRoute(ctx, "/qps/rest/2.0/get/am/hostasset/{id}")
+	if got := routeFor(ctx, "/qps/rest/2.0/get/am/hostasset/42"); got != "/qps/rest/2.0/get/am/hostasset/{id}" {
+		t.Errorf("routeFor() with route = %q", got)
+	}
+}
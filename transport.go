@@ -0,0 +1,240 @@
+package godefaultapi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxDrainSize is the most of an unread response body that is read before
+// closing it so that its connection can be reused. Larger remainders are
+// cheaper to drop with the connection.
+const maxDrainSize = 256 << 10
+
+// TransportConfig tunes how the client opens and reuses connections
+type TransportConfig struct {
+	// HTTP2 negotiates HTTP/2 with servers that support it
+	HTTP2 bool
+	// MaxIdleConns limits idle connections across all hosts, zero is unlimited
+	MaxIdleConns int
+	// MaxIdleConnsPerHost limits idle connections kept for each host
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits connections to each host, zero is unlimited
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open
+	IdleConnTimeout time.Duration
+	// KeepAlive is the TCP keep-alive probe interval, negative disables probes
+	KeepAlive time.Duration
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool
+}
+
+// DefaultTransportConfig returns a default transport configuration. It keeps
+// more idle connections per host than net/http, since a client usually
+// talks to a single API server.
+func DefaultTransportConfig() *TransportConfig {
+	return &TransportConfig{
+		HTTP2:               true,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+	}
+}
+
+// SetTransportConfig applies connection settings to the client's transport.
+// TLS settings of an *http.Transport set earlier are kept. Other custom
+// RoundTrippers cannot be tuned and are left in place with an error; wrap
+// them with Use instead of setting them as the transport.
+func (c *Client) SetTransportConfig(config *TransportConfig) error {
+	if config == nil {
+		return fmt.Errorf("transport config is nil")
+	}
+	base := http.DefaultTransport.(*http.Transport)
+	if c.httpClient.Transport != nil {
+		var ok bool
+		if base, ok = c.httpClient.Transport.(*http.Transport); !ok {
+			return fmt.Errorf("cannot apply transport config to custom transport %T", c.httpClient.Transport)
+		}
+	}
+	transport := base.Clone()
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(config.HTTP2)
+	transport.Protocols = protocols
+	transport.ForceAttemptHTTP2 = config.HTTP2
+	if !config.HTTP2 {
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		if transport.TLSClientConfig != nil {
+			// Stop advertising h2 in ALPN, which a cloned config may do. The
+			// clone shares its NextProtos with the base transport, so copy them.
+			transport.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(transport.TLSClientConfig.NextProtos), func(proto string) bool {
+				return proto == "h2"
+			})
+		}
+	}
+
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = config.MaxConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	transport.DisableKeepAlives = config.DisableKeepAlives
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: config.KeepAlive,
+	}).DialContext
+
+	c.httpClient.Transport = transport
+	return nil
+}
+
+// SetConnStats enables per-attempt connection statistics. They are read with
+// ConnStatsFromResponse, added to attempt spans and logged at debug level.
+func (c *Client) SetConnStats(enabled bool) {
+	c.connStats = enabled
+}
+
+// ConnStats describes the connection of a request attempt and its timing
+type ConnStats struct {
+	// Reused reports whether an existing connection was used
+	Reused bool
+	// IdleTime is how long a reused connection was idle
+	IdleTime time.Duration
+	// RemoteAddr is the address of the server
+	RemoteAddr string
+	// Protocol is the response protocol, e.g. "HTTP/2.0"
+	Protocol string
+	// DNS, Connect and TLS are the durations of the DNS lookup, TCP connect
+	// and TLS handshake, zero for reused connections
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB is the time from requesting a connection to the first response byte
+	TTFB time.Duration
+}
+
+// connStatsKey is the context key for an attempt's connection trace
+type connStatsKey struct{}
+
+// connTrace collects ConnStats from httptrace callbacks, which may run on
+// other goroutines
+type connTrace struct {
+	mu           sync.Mutex
+	stats        ConnStats
+	gotConn      bool
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+}
+
+// withConnTrace returns a context that traces the connection of a request
+func withConnTrace(ctx context.Context) context.Context {
+	t := &connTrace{}
+	ctx = context.WithValue(ctx, connStatsKey{}, t)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.start = time.Now()
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.stats.DNS = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			if err == nil && t.stats.Connect == 0 {
+				t.stats.Connect = time.Since(t.connectStart)
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.stats.TLS = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn = true
+			t.stats.Reused = info.Reused
+			t.stats.IdleTime = info.IdleTime
+			if info.Conn != nil {
+				t.stats.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.stats.TTFB = time.Since(t.start)
+			t.mu.Unlock()
+		},
+	})
+}
+
+// setProtocol records the protocol of the response
+func (t *connTrace) setProtocol(proto string) {
+	t.mu.Lock()
+	t.stats.Protocol = proto
+	t.mu.Unlock()
+}
+
+// connStatsFromContext returns the connection stats traced in ctx, if a
+// connection was used
+func connStatsFromContext(ctx context.Context) (ConnStats, bool) {
+	t, ok := ctx.Value(connStatsKey{}).(*connTrace)
+	if !ok {
+		return ConnStats{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats, t.gotConn
+}
+
+// ConnStatsFromResponse returns the connection stats of the attempt that
+// received resp, for use in hooks and middleware. It reports false unless
+// SetConnStats is enabled and the response came over a connection, rather
+// than from the cache or a dry run.
+func ConnStatsFromResponse(resp *http.Response) (ConnStats, bool) {
+	if resp == nil || resp.Request == nil {
+		return ConnStats{}, false
+	}
+	stats, ok := connStatsFromContext(resp.Request.Context())
+	if ok && stats.Protocol == "" {
+		stats.Protocol = resp.Proto
+	}
+	return stats, ok
+}
+
+// drainBody reads what is left of a small response body and closes it, so
+// that its connection can be reused
+func drainBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, maxDrainSize))
+	body.Close()
+}
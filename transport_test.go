@@ -0,0 +1,185 @@
+package godefaultapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// connStatsRecorder collects the connection stats of every response
+func connStatsRecorder(client *Client) *[]ConnStats {
+	var stats []ConnStats
+	client.OnAfterResponse(func(resp *http.Response) {
+		if s, ok := ConnStatsFromResponse(resp); ok {
+			stats = append(stats, s)
+		}
+	})
+	return &stats
+}
+
+func TestConnStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<OK/>"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetConnStats(true)
+	stats := connStatsRecorder(client)
+	for i := 0; i < 2; i++ {
+		if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	if len(*stats) != 2 {
+		t.Fatalf("stats = %d, want 2", len(*stats))
+	}
+	first, second := (*stats)[0], (*stats)[1]
+	if first.Reused || first.Connect <= 0 || first.TTFB <= 0 {
+		t.Errorf("first = %+v, want a new connection with timings", first)
+	}
+	if !second.Reused || second.Connect != 0 {
+		t.Errorf("second = %+v, want the connection reused", second)
+	}
+	if first.Protocol != "HTTP/1.1" || !strings.HasPrefix(server.URL, "http://"+first.RemoteAddr) {
+		t.Errorf("protocol = %q, remote = %q", first.Protocol, first.RemoteAddr)
+	}
+}
+
+func TestConnStatsDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	stats := connStatsRecorder(client)
+	if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(*stats) != 0 {
+		t.Errorf("stats = %+v, want none without SetConnStats", *stats)
+	}
+}
+
+func TestRateLimitRetryReusesConnection(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("X-RateLimit-Reset", "soon")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(strings.Repeat("x", 64<<10)))
+			return
+		}
+		w.Write([]byte("<OK/>"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetRateLimitConfig(&RateLimitConfig{HeaderName: "X-RateLimit-Reset", MaxRetries: 1, DefaultWaitTime: time.Millisecond})
+	client.SetConnStats(true)
+	stats := connStatsRecorder(client)
+	if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(*stats) != 2 || !(*stats)[1].Reused {
+		t.Errorf("stats = %+v, want the retry to reuse the connection", *stats)
+	}
+}
+
+func TestSetTransportConfigHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, http2 := range []bool{true, false} {
+		client := NewClient(server.URL)
+		client.httpClient.Transport = server.Client().Transport
+		config := DefaultTransportConfig()
+		config.HTTP2 = http2
+		if err := client.SetTransportConfig(config); err != nil {
+			t.Fatalf("SetTransportConfig() error = %v", err)
+		}
+
+		var proto string
+		client.OnAfterResponse(func(resp *http.Response) { proto = resp.Proto })
+		if err := client.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		want := "HTTP/1.1"
+		if http2 {
+			want = "HTTP/2.0"
+		}
+		if proto != want {
+			t.Errorf("HTTP2 = %v: protocol = %s, want %s", http2, proto, want)
+		}
+	}
+
+	client := NewClient("http://unused.invalid")
+	if err := client.SetTransportConfig(&TransportConfig{MaxIdleConnsPerHost: 4, DisableKeepAlives: true}); err != nil {
+		t.Fatalf("SetTransportConfig() error = %v", err)
+	}
+	if tr := client.httpClient.Transport.(*http.Transport); tr.MaxIdleConnsPerHost != 4 || !tr.DisableKeepAlives {
+		t.Errorf("transport = %+v, want limits applied", tr)
+	}
+}
+
+func TestSetTransportConfigKeepsDefaultTransport(t *testing.T) {
+	// A TLS request sets up the ALPN protocols of the default transport
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	if resp, err := http.DefaultTransport.RoundTrip(httptest.NewRequest(http.MethodGet, server.URL, nil)); err == nil {
+		resp.Body.Close()
+	}
+	defaults := http.DefaultTransport.(*http.Transport).TLSClientConfig
+	if defaults == nil {
+		t.Skip("default transport has no TLS config")
+	}
+	want := slices.Clone(defaults.NextProtos)
+
+	config := DefaultTransportConfig()
+	config.HTTP2 = false
+	client := NewClient(server.URL)
+	if err := client.SetTransportConfig(config); err != nil {
+		t.Fatalf("SetTransportConfig() error = %v", err)
+	}
+
+	if got := defaults.NextProtos; !slices.Equal(got, want) {
+		t.Errorf("default transport NextProtos = %q, want %q", got, want)
+	}
+	transport := client.httpClient.Transport.(*http.Transport)
+	if got := transport.TLSClientConfig.NextProtos; slices.Contains(got, "h2") {
+		t.Errorf("client NextProtos = %q, want no h2", got)
+	}
+}
+
+func TestSetTransportConfigErrors(t *testing.T) {
+	client := NewClient("http://unused.invalid")
+	if err := client.SetTransportConfig(nil); err == nil {
+		t.Error("SetTransportConfig(nil) error = nil, want an error")
+	}
+
+	// A custom RoundTripper is kept rather than silently replaced
+	custom := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("custom transport")
+	})
+	client.httpClient.Transport = custom
+	err := client.SetTransportConfig(DefaultTransportConfig())
+	if err == nil || !strings.Contains(err.Error(), "custom transport") {
+		t.Errorf("SetTransportConfig() error = %v, want custom transport error", err)
+	}
+	if _, ok := client.httpClient.Transport.(RoundTripperFunc); !ok {
+		t.Errorf("transport = %T, want the custom RoundTripper kept", client.httpClient.Transport)
+	}
+}
@@ -0,0 +1,233 @@
+package godefaultapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a significant line of a YAML document
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseSimpleYAML parses the subset of YAML used by configuration files:
+// nested mappings, block and flow sequences of scalars, quoted and plain
+// scalars, and comments. Scalars are returned as strings, mappings as
+// map[string]interface{} and sequences as []interface{}.
+func parseSimpleYAML(data []byte) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " ")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].number)
+	}
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("line %d: document must be a mapping", lines[0].number)
+	}
+	return doc, nil
+}
+
+// parseYAMLBlock parses the mapping or sequence starting at lines[i] with
+// the given indentation, returning it and the index of the next line
+func parseYAMLBlock(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if isYAMLSequenceItem(lines[i].text) {
+		var items []interface{}
+		for i < len(lines) && lines[i].indent == indent && isYAMLSequenceItem(lines[i].text) {
+			item := strings.TrimSpace(strings.TrimPrefix(lines[i].text, "-"))
+			if _, _, isMapping := cutYAMLKey(item); isMapping {
+				return nil, 0, fmt.Errorf("line %d: sequences of mappings are not supported", lines[i].number)
+			}
+			value, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", lines[i].number, err)
+			}
+			items = append(items, value)
+			i++
+		}
+		return items, i, nil
+	}
+
+	mapping := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key, rest, ok := cutYAMLKey(line.text)
+		if !ok {
+			return nil, 0, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, 0, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		i++
+
+		if rest != "" {
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			mapping[key] = value
+			continue
+		}
+
+		// A nested block, or a sequence at the same indentation as its key
+		switch {
+		case i < len(lines) && lines[i].indent > indent:
+			value, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			mapping[key], i = value, next
+		case i < len(lines) && lines[i].indent == indent && isYAMLSequenceItem(lines[i].text):
+			value, next, err := parseYAMLBlock(lines, i, indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			mapping[key], i = value, next
+		default:
+			mapping[key] = nil
+		}
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, 0, fmt.Errorf("line %d: unexpected indentation", lines[i].number)
+	}
+	return mapping, i, nil
+}
+
+// isYAMLSequenceItem reports whether a line is a block sequence item
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// cutYAMLKey splits a "key: value" line
+func cutYAMLKey(text string) (key, rest string, ok bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		quote := text[:1]
+		end := strings.Index(text[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		key, text = text[1:end+1], text[end+2:]
+		if !strings.HasPrefix(text, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(text[1:]), true
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLScalar parses a scalar or a flow sequence of scalars
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch {
+	case text == "" || text == "~" || text == "null":
+		return nil, nil
+	case strings.HasPrefix(text, `"`):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated sequence %s", text)
+		}
+		items := []interface{}{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, part := range splitYAMLFlow(inner) {
+			item, err := parseYAMLScalar(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case strings.HasPrefix(text, "{"), strings.HasPrefix(text, "|"), strings.HasPrefix(text, ">"),
+		strings.HasPrefix(text, "&"), strings.HasPrefix(text, "*"), strings.HasPrefix(text, "!"):
+		return nil, fmt.Errorf("unsupported YAML syntax %s", text)
+	default:
+		return text, nil
+	}
+}
+
+// splitYAMLFlow splits the items of a flow sequence at commas outside
+// quoted scalars
+func splitYAMLFlow(inner string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(inner); i++ {
+		ch := inner[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ',':
+			parts = append(parts, inner[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, inner[start:])
+}
+
+// stripYAMLComment removes a trailing comment, ignoring # inside quoted
+// scalars
+func stripYAMLComment(line string) string {
+	var quote, prev byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case (ch == '"' || ch == '\'') && (prev == 0 || prev == ':' || prev == '-' || prev == '[' || prev == ','):
+			quote = ch
+		case ch == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+		if ch != ' ' && ch != '\t' {
+			prev = ch
+		}
+	}
+	return line
+}
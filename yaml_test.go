@@ -0,0 +1,68 @@
+package godefaultapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSimpleYAML(t *testing.T) {
+	doc := `
+# client settings
+base_url: US3
+failover_urls:
+- EU1
+- "https://qualysapi.example.com" # fallback
+headers:
+  X-Requested-With: 'go default api'
+  Accept-Language: "en #1"
+rate_limit:
+  max_retries: 5
+tags: [a, 'b', "c"]
+quoted: ["a,b", 'it''s, here', c]
+empty:
+`
+	got, err := parseSimpleYAML([]byte(doc))
+	if err != nil {
+		t.Fatalf("parseSimpleYAML() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"base_url":      "US3",
+		"failover_urls": []interface{}{"EU1", "https://qualysapi.example.com"},
+		"headers": map[string]interface{}{
+			"X-Requested-With": "go default api",
+			"Accept-Language":  "en #1",
+		},
+		"rate_limit": map[string]interface{}{"max_retries": "5"},
+		"tags":       []interface{}{"a", "b", "c"},
+		"quoted":     []interface{}{"a,b", "it's, here", "c"},
+		"empty":      nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSimpleYAML() = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseSimpleYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{"duplicate key", "a: 1\na: 2\n", `line 2: duplicate key "a"`},
+		{"bad indentation", "a:\n    b: 1\n  c: 2\n", "line 3: unexpected indentation"},
+		{"tab", "a:\n\tb: 1\n", "line 2: tabs are not allowed"},
+		{"not a mapping", "- a\n", "line 1: document must be a mapping"},
+		{"missing colon", "a: 1\nb\n", `line 2: expected "key: value"`},
+		{"anchor", "a: &x 1\n", "line 1: unsupported YAML syntax"},
+		{"sequence of mappings", "a:\n  - b: 1\n", "line 2: sequences of mappings are not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSimpleYAML([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseSimpleYAML() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}